@@ -0,0 +1,134 @@
+package nico
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/178inaba/nico/internal/sqlite"
+)
+
+// ReadFirefoxCookies reads the niconico cookies
+// from cookies.sqlite of name in a Firefox profile.
+// Domain of the returned cookie has a leading dot if it is not host-only.
+func ReadFirefoxCookies(name string) ([]*http.Cookie, error) {
+	db, err := sqlite.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Rows("moz_cookies")
+	if err != nil {
+		return nil, err
+	}
+
+	var cookies []*http.Cookie
+	for _, row := range rows {
+		host, _ := row["host"].(string)
+		if !isNiconicoDomain(host) {
+			continue
+		}
+		if oa, _ := row["originAttributes"].(string); oa != "" {
+			// Cookies of containers and private windows.
+			continue
+		}
+		name, _ := row["name"].(string)
+		value, _ := row["value"].(string)
+		path, _ := row["path"].(string)
+		expiry, _ := row["expiry"].(int64)
+		isSecure, _ := row["isSecure"].(int64)
+		isHTTPOnly, _ := row["isHttpOnly"].(int64)
+
+		c := &http.Cookie{
+			Name:     name,
+			Value:    value,
+			Domain:   strings.ToLower(host),
+			Path:     path,
+			Secure:   isSecure != 0,
+			HttpOnly: isHTTPOnly != 0,
+		}
+		if expiry > 1e11 {
+			// Newer Firefox saves expiry in milliseconds.
+			c.Expires = time.Unix(0, expiry*int64(time.Millisecond))
+		} else if expiry > 0 {
+			c.Expires = time.Unix(expiry, 0)
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, nil
+}
+
+// ReadCookiesTxt reads the niconico cookies from the Netscape cookies.txt of name.
+func ReadCookiesTxt(name string) ([]*http.Cookie, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	all, err := ParseNetscapeCookies(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var cookies []*http.Cookie
+	for _, c := range all {
+		if isNiconicoDomain(c.Domain) {
+			cookies = append(cookies, c)
+		}
+	}
+	return cookies, nil
+}
+
+// NewClientFromFirefox returns a client with the login session of Firefox.
+// The session is verified by VerifySession.
+func NewClientFromFirefox(ctx context.Context, name string, opts ...Option) (*Client, error) {
+	cookies, err := ReadFirefoxCookies(name)
+	if err != nil {
+		return nil, err
+	}
+	return newClientFromCookies(ctx, cookies, opts...)
+}
+
+// NewClientFromCookiesTxt returns a client with the login session
+// exported to the Netscape cookies.txt of name.
+// The session is verified by VerifySession.
+func NewClientFromCookiesTxt(ctx context.Context, name string, opts ...Option) (*Client, error) {
+	cookies, err := ReadCookiesTxt(name)
+	if err != nil {
+		return nil, err
+	}
+	return newClientFromCookies(ctx, cookies, opts...)
+}
+
+func newClientFromCookies(ctx context.Context, cookies []*http.Cookie, opts ...Option) (*Client, error) {
+	c := NewClient(opts...)
+	now := time.Now()
+	for _, cookie := range cookies {
+		if !cookie.Expires.IsZero() && cookie.Expires.Before(now) {
+			continue
+		}
+		u := &url.URL{Scheme: "https", Host: strings.TrimPrefix(cookie.Domain, "."), Path: "/"}
+		cc := *cookie
+		if !strings.HasPrefix(cc.Domain, ".") {
+			cc.Domain = ""
+		}
+		c.Jar.SetCookies(u, []*http.Cookie{&cc})
+		if cookie.Name == "user_session" {
+			c.UserSession = cookie.Value
+		}
+	}
+	if c.UserSession == "" {
+		return nil, fmt.Errorf("%w: user_session cookie not found", ErrNotLoggedIn)
+	}
+	if _, err := c.VerifySession(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func isNiconicoDomain(domain string) bool {
+	return domainMatch(strings.TrimPrefix(strings.ToLower(domain), "."), "nicovideo.jp")
+}
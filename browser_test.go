@@ -0,0 +1,66 @@
+package nico
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFirefoxCookies(t *testing.T) {
+	cookies, err := ReadFirefoxCookies("testdata/cookies.sqlite")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if len(cookies) != 3 {
+		t.Fatalf("want %d but %d", 3, len(cookies))
+	}
+	c := cookies[0]
+	if c.Name != "user_session" || c.Value != "user_session_2525_foobarbaz" || c.Domain != ".nicovideo.jp" {
+		t.Fatalf("unexpected cookie: %v", c)
+	}
+	if !c.Secure || !c.HttpOnly || c.Expires.Unix() != 4102444800 {
+		t.Fatalf("unexpected cookie: %v", c)
+	}
+}
+
+func TestNewClientFromBrowser(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		us, err := r.Cookie("user_session")
+		if err != nil || us.Value != "user_session_2525_foobarbaz" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, `{"meta":{"status":200},"data":{"user":{"id":2525,"nickname":"foo","isPremium":false}}}`)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	c, err := NewClientFromFirefox(ctx, "testdata/cookies.sqlite", WithNvapiBaseURL(ts.URL))
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if c.UserSession != "user_session_2525_foobarbaz" {
+		t.Fatalf("want %q but %q", "user_session_2525_foobarbaz", c.UserSession)
+	}
+
+	c, err = NewClientFromCookiesTxt(ctx, "testdata/cookies.txt", WithNvapiBaseURL(ts.URL))
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if got := len(c.Jar.(*Jar).AllCookies()); got != 2 {
+		t.Fatalf("want %d but %d", 2, got)
+	}
+
+	name := filepath.Join(t.TempDir(), "cookies.txt")
+	j := NewJar()
+	if err := j.SaveFile(name, CookieFormatNetscape); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if _, err := NewClientFromCookiesTxt(ctx, name, WithNvapiBaseURL(ts.URL)); !errors.Is(err, ErrNotLoggedIn) {
+		t.Fatalf("want %v but %v", ErrNotLoggedIn, err)
+	}
+}
@@ -0,0 +1,87 @@
+package nico
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+// RawFrame is a frame of the comment server of an unknown element.
+// Name is empty if the frame is not a valid XML.
+type RawFrame struct {
+	Name string
+	Data []byte
+}
+
+func (f *RawFrame) comment() {}
+
+// CommentDecoder reads and decodes the NUL-terminated frames of the comment server.
+type CommentDecoder struct {
+	r *bufio.Reader
+
+	// partial is the head of the frame read before the error.
+	partial []byte
+}
+
+// NewCommentDecoder returns new CommentDecoder that reads from r.
+func NewCommentDecoder(r io.Reader) *CommentDecoder {
+	return &CommentDecoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next frame and returns Thread, Chat, ChatResult or RawFrame
+// by the root element. It returns the error only if reading fails.
+// io.ErrUnexpectedEOF is returned if the stream ends in the middle of a frame.
+// Decode can be called again after a timeout error and resumes the frame.
+func (d *CommentDecoder) Decode() (Comment, error) {
+	for {
+		b, err := d.r.ReadBytes(0)
+		if err != nil {
+			d.partial = append(d.partial, b...)
+			if err == io.EOF && len(d.partial) > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if len(d.partial) > 0 {
+			b = append(d.partial, b...)
+			d.partial = nil
+		}
+		b = bytes.TrimSpace(b[:len(b)-1])
+		if len(b) == 0 {
+			continue
+		}
+		return decodeFrame(b), nil
+	}
+}
+
+// decodeFrame decodes b by the name of the root element.
+func decodeFrame(b []byte) Comment {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return &RawFrame{Data: b}
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var cmt Comment
+		switch se.Name.Local {
+		case "thread":
+			cmt = &Thread{}
+		case "chat":
+			cmt = &Chat{}
+		case "chat_result":
+			cmt = &ChatResult{}
+		default:
+			return &RawFrame{Name: se.Name.Local, Data: b}
+		}
+		if err := dec.DecodeElement(cmt, &se); err != nil {
+			return &RawFrame{Name: se.Name.Local, Data: b}
+		}
+		return cmt
+	}
+}
@@ -0,0 +1,141 @@
+package nico
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommentDecoder_Decode(t *testing.T) {
+	frames := []string{
+		`<thread resultcode="0" thread="1234567890" last_res="10" ticket="0x12345678" revision="1" server_time="1500000000"/>`,
+		`<chat thread="1234567890" no="11" vpos="100" date="1500000001" user_id="2525" premium="1">foo &amp; bar</chat>`,
+		`<chat_result thread="1234567890" status="0" no="12"/>`,
+		``,
+		`<ping>rs:0</ping>`,
+		`<chat`,
+	}
+	dec := NewCommentDecoder(strings.NewReader(strings.Join(frames, "\x00") + "\x00<chat>partial"))
+
+	want := []Comment{
+		&Thread{XMLName: xmlName("thread"), Resultcode: 0, Thread: 1234567890, LastRes: 10, Ticket: "0x12345678", Revision: 1, ServerTime: 1500000000},
+		&Chat{XMLName: xmlName("chat"), Thread: 1234567890, No: 11, Vpos: 100, Date: 1500000001, UserID: "2525", Premium: 1, Comment: "foo & bar"},
+		&ChatResult{XMLName: xmlName("chat_result"), Thread: 1234567890, Status: 0, No: 12},
+		&RawFrame{Name: "ping", Data: []byte("<ping>rs:0</ping>")},
+		&RawFrame{Data: []byte("<chat")},
+	}
+	for _, w := range want {
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Fatalf("want %+v but %+v", w, got)
+		}
+	}
+	if _, err := dec.Decode(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("want %v but %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestCommentDecoder_DecodeResume(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	dec := NewCommentDecoder(client)
+
+	go server.Write([]byte(`<chat thread="1" no="1">fo`))
+	client.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	var ne net.Error
+	if _, err := dec.Decode(); !errors.As(err, &ne) || !ne.Timeout() {
+		t.Fatalf("want timeout but %v", err)
+	}
+
+	client.SetReadDeadline(time.Time{})
+	go server.Write([]byte("o</chat>\x00"))
+	got, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if chat, ok := got.(*Chat); !ok || chat.Comment != "foo" {
+		t.Fatalf("want chat foo but %+v", got)
+	}
+}
+
+func TestLiveClient_StreamingComment(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	lc := &LiveClient{Client: NewClient(), PlayerStatus: &PlayerStatus{Ms: Ms{Thread: 1234567890}}, conn: client}
+
+	go func() {
+		buf := make([]byte, 1024)
+		server.Read(buf)
+		server.Write([]byte(`<thread thread="1234567890"/>` + "\x00" + `<chat thread="1234567890" no="1">foo</chat>` + "\x00"))
+		server.Close()
+	}()
+
+	ch, err := lc.StreamingComment(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	var got []Comment
+	for cmt := range ch {
+		got = append(got, cmt)
+	}
+	if len(got) != 3 {
+		t.Fatalf("want %d but %d: %v", 3, len(got), got)
+	}
+	if _, ok := got[0].(*Thread); !ok {
+		t.Fatalf("want *Thread but %T", got[0])
+	}
+	if chat, ok := got[1].(*Chat); !ok || chat.Comment != "foo" {
+		t.Fatalf("want chat foo but %+v", got[1])
+	}
+	if ce, ok := got[2].(*CommentError); !ok || !errors.Is(ce, io.EOF) {
+		t.Fatalf("want %v but %+v", io.EOF, got[2])
+	}
+}
+
+func TestLiveClient_StreamingCommentCancel(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	lc := &LiveClient{Client: NewClient(), PlayerStatus: &PlayerStatus{}, conn: client}
+
+	go func() {
+		buf := make([]byte, 1024)
+		server.Read(buf)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := lc.StreamingComment(ctx, 0)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	cancel()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case cmt, ok := <-ch:
+			if !ok {
+				return
+			}
+			if ce, isErr := cmt.(*CommentError); !isErr || !errors.Is(ce, context.Canceled) {
+				t.Fatalf("want %v but %+v", context.Canceled, cmt)
+			}
+		case <-timeout:
+			t.Fatalf("the channel should be closed on cancel")
+		}
+	}
+}
+
+func xmlName(local string) xml.Name {
+	return xml.Name{Local: local}
+}
@@ -0,0 +1,90 @@
+package nico
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestClient_Concurrent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "user_session", Value: "foobarbaz"})
+			http.Redirect(w, r, "/top", http.StatusFound)
+		case "/motion/co1234567":
+			http.Redirect(w, r, "/motion/co1234567/done", http.StatusFound)
+		case "/leave/co1234567":
+			if r.Method == http.MethodPost {
+				http.Redirect(w, r, "/leave/co1234567/done", http.StatusFound)
+				return
+			}
+			io.WriteString(w, `<form class="leave_form"><input name="commit" value="yes"></form>`)
+		case "/api/getplayerstatus":
+			http.Redirect(w, r, "/api/getplayerstatus/redirected", http.StatusFound)
+		case "/api/getplayerstatus/redirected":
+			io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><getplayerstatus status="ok"></getplayerstatus>`)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithLoginURL(ts.URL+"/login"), WithLiveBaseURL(ts.URL), WithCommunityBaseURL(ts.URL))
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Login(ctx, "foo@foo.com", "bar"); err != nil {
+				t.Errorf("should not be fail: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := c.FollowCommunity(ctx, "co1234567"); err != nil {
+				t.Errorf("should not be fail: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := c.LeaveCommunity(ctx, "co1234567"); err != nil {
+				t.Errorf("should not be fail: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetPlayerStatus(ctx, "lv123456789"); err != nil {
+				t.Errorf("should not be fail: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if c.CheckRedirect != nil {
+		t.Fatalf("CheckRedirect should not be modified")
+	}
+}
+
+func TestClient_CheckRedirect(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	var n int
+	c := NewClient(WithLiveBaseURL(ts.URL))
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		n++
+		return http.ErrUseLastResponse
+	}
+	if _, err := c.GetPlayerStatus(context.Background(), "lv123456789"); err == nil {
+		t.Fatalf("should be fail: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("want %d but %d", 1, n)
+	}
+}
@@ -0,0 +1,121 @@
+package nico
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ContentsIDMain is the ID of the main contents.
+const ContentsIDMain = "main"
+
+// ContentSource is a parsed source of Contents.
+type ContentSource struct {
+	ID string
+
+	// Scheme is the prefix of the contents such as "rtmp" or "case".
+	Scheme string
+
+	// URL is the URL of the contents server
+	// such as "rtmp://nlpoca123.live.nicovideo.jp:1935/publicorigin/170714_00_0/".
+	URL string
+
+	// Stream is the name of the stream to play.
+	Stream string
+
+	StartTime UnixTime
+
+	// Offset is the start time relative to the start of the broadcast.
+	Offset time.Duration
+
+	DisableAudio bool
+	DisableVideo bool
+}
+
+// ParseContents parses the contents in the form of "scheme:url,stream".
+// The stream is empty if the contents has no comma.
+func ParseContents(c Contents) (ContentSource, error) {
+	s := strings.TrimSpace(c.Contents)
+	i := strings.Index(s, ":")
+	if i <= 0 {
+		return ContentSource{}, fmt.Errorf("invalid contents: %q", c.Contents)
+	}
+	u, stream := s[i+1:], ""
+	if j := strings.Index(u, ","); j >= 0 {
+		u, stream = u[:j], u[j+1:]
+	}
+	if u == "" {
+		return ContentSource{}, fmt.Errorf("invalid contents: %q", c.Contents)
+	}
+	return ContentSource{
+		ID:           c.ID,
+		Scheme:       s[:i],
+		URL:          u,
+		Stream:       stream,
+		StartTime:    UnixTime(c.StartTime),
+		DisableAudio: c.DisableAudio != 0,
+		DisableVideo: c.DisableVideo != 0,
+	}, nil
+}
+
+// ContentSources returns the parsed sources of all contents.
+// The malformed contents are skipped, and the errors of them are returned
+// joined together with the sources of the others.
+func (ps *PlayerStatus) ContentSources() ([]ContentSource, error) {
+	srcs := make([]ContentSource, 0, len(ps.Stream.ContentsList.Contents))
+	var errs []error
+	for _, c := range ps.Stream.ContentsList.Contents {
+		src, err := ParseContents(c)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("contents %s: %w", c.ID, err))
+			continue
+		}
+		if src.StartTime != 0 && ps.Stream.StartTime != 0 {
+			src.Offset = src.StartTime.Time().Sub(ps.StartAt())
+		}
+		srcs = append(srcs, src)
+	}
+	return srcs, errors.Join(errs...)
+}
+
+// Playback is the information to play the broadcast with RTMP.
+type Playback struct {
+	// URL is the RTMP URL to connect.
+	URL string
+
+	// Ticket is the ticket to pass with the connect command.
+	Ticket string
+
+	// Source is the source of the main contents.
+	Source ContentSource
+}
+
+// Playback returns the information to play the main contents.
+// The first valid contents is used if there is no main contents,
+// and the malformed other contents are ignored.
+// It returns ErrContentsNotFound if the player status has no contents or RTMP URL.
+func (ps *PlayerStatus) Playback() (*Playback, error) {
+	if ps.Rtmp.URL == "" {
+		return nil, ErrContentsNotFound
+	}
+	srcs, err := ps.ContentSources()
+	for _, s := range srcs {
+		if s.ID == ContentsIDMain {
+			return &Playback{URL: ps.Rtmp.URL, Ticket: ps.Rtmp.Ticket, Source: s}, nil
+		}
+	}
+	for _, c := range ps.Stream.ContentsList.Contents {
+		if c.ID == ContentsIDMain {
+			// The main contents is malformed.
+			return nil, err
+		}
+	}
+	if len(srcs) == 0 {
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrContentsNotFound
+	}
+	return &Playback{URL: ps.Rtmp.URL, Ticket: ps.Rtmp.Ticket, Source: srcs[0]}, nil
+}
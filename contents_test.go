@@ -0,0 +1,95 @@
+package nico
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseContents(t *testing.T) {
+	tests := []struct {
+		contents Contents
+		want     ContentSource
+	}{
+		{
+			Contents{ID: "main", StartTime: 1500000000, Contents: "rtmp:rtmp://nlpoca123.live.nicovideo.jp:1935/publicorigin/170714_00_0/,lv301234567?1500000000:30:0123456789abcdef"},
+			ContentSource{ID: "main", Scheme: "rtmp", URL: "rtmp://nlpoca123.live.nicovideo.jp:1935/publicorigin/170714_00_0/", Stream: "lv301234567?1500000000:30:0123456789abcdef", StartTime: 1500000000},
+		},
+		{
+			Contents{ID: "sub", DisableAudio: 1, Contents: "case:http://live.nicovideo.jp/sub.swf"},
+			ContentSource{ID: "sub", Scheme: "case", URL: "http://live.nicovideo.jp/sub.swf", DisableAudio: true},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ParseContents(tt.contents)
+		if err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("want %+v but %+v", tt.want, got)
+		}
+	}
+
+	for _, s := range []string{"", "rtmp", ":rtmp://example.com/", "rtmp:,stream"} {
+		if _, err := ParseContents(Contents{Contents: s}); err == nil {
+			t.Fatalf("should be fail: %q", s)
+		}
+	}
+}
+
+func TestPlayerStatus_Playback(t *testing.T) {
+	ts := newPlayerStatusServer(t)
+	defer ts.Close()
+
+	c := NewClient(WithLiveBaseURL(ts.URL))
+	ps, err := c.GetPlayerStatus(context.Background(), "lv301234567")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	srcs, err := ps.ContentSources()
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if len(srcs) != 2 {
+		t.Fatalf("want %d but %d", 2, len(srcs))
+	}
+	if srcs[0].Offset != 0 || srcs[1].Offset != 10*time.Minute {
+		t.Fatalf("want offsets %v, %v but %v, %v", time.Duration(0), 10*time.Minute, srcs[0].Offset, srcs[1].Offset)
+	}
+
+	// The main contents is chosen even if it is not the first.
+	ps.Stream.ContentsList.Contents[0], ps.Stream.ContentsList.Contents[1] = ps.Stream.ContentsList.Contents[1], ps.Stream.ContentsList.Contents[0]
+	pb, err := ps.Playback()
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	want := &Playback{
+		URL:    "rtmp://nlpoca123.live.nicovideo.jp:1935/liveedge/live_170714_00_0",
+		Ticket: "2525:lv301234567:0:1500000000:0123456789abcdef",
+		Source: srcs[0],
+	}
+	if !reflect.DeepEqual(pb, want) {
+		t.Fatalf("want %+v but %+v", want, pb)
+	}
+
+	if _, err := (&PlayerStatus{}).Playback(); err != ErrContentsNotFound {
+		t.Fatalf("want %v but %v", ErrContentsNotFound, err)
+	}
+
+	// The malformed sub contents does not prevent the main contents.
+	ps.Stream.ContentsList.Contents[0].Contents = "malformed"
+	srcs, err = ps.ContentSources()
+	if err == nil || len(srcs) != 1 || srcs[0].ID != ContentsIDMain {
+		t.Fatalf("want the main contents and the error but %+v, %v", srcs, err)
+	}
+	if pb, err := ps.Playback(); err != nil || !reflect.DeepEqual(pb, want) {
+		t.Fatalf("want %+v but %+v, %v", want, pb, err)
+	}
+
+	ps.Stream.ContentsList.Contents[1].Contents = "malformed"
+	if _, err := ps.Playback(); err == nil {
+		t.Fatalf("should be fail: %v", err)
+	}
+}
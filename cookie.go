@@ -0,0 +1,382 @@
+package nico
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CookieFormat is a file format of saved cookies.
+type CookieFormat int
+
+// Cookie file formats.
+const (
+	CookieFormatJSON CookieFormat = iota
+	CookieFormatNetscape
+)
+
+// Jar is an http.CookieJar that can save and load its cookies.
+type Jar struct {
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	entries map[string]*http.Cookie
+}
+
+// NewJar returns new empty Jar.
+func NewJar() *Jar {
+	// cookiejar.New returns no error when options is nil.
+	jar, _ := cookiejar.New(nil)
+	return &Jar{jar: jar, entries: map[string]*http.Cookie{}}
+}
+
+// SetCookies implements the SetCookies method of the http.CookieJar interface.
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar.SetCookies(u, cookies)
+
+	host := u.Hostname()
+	now := time.Now()
+	for _, c := range cookies {
+		e := *c
+		if e.Domain == "" {
+			e.Domain = host
+		} else {
+			d := strings.TrimPrefix(strings.ToLower(e.Domain), ".")
+			if !domainMatch(host, d) {
+				continue
+			}
+			e.Domain = "." + d
+		}
+		if e.Path == "" || e.Path[0] != '/' {
+			e.Path = defaultCookiePath(u.Path)
+		}
+		if e.MaxAge > 0 {
+			e.Expires = now.Add(time.Duration(e.MaxAge) * time.Second)
+		}
+		e.MaxAge = 0
+		e.Raw = ""
+		e.Unparsed = nil
+
+		key := e.Domain + ";" + e.Path + ";" + e.Name
+		if c.MaxAge < 0 || (!e.Expires.IsZero() && !e.Expires.After(now)) {
+			delete(j.entries, key)
+			continue
+		}
+		j.entries[key] = &e
+	}
+}
+
+// Cookies implements the Cookies method of the http.CookieJar interface.
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jar.Cookies(u)
+}
+
+// AllCookies returns all unexpired cookies in the jar.
+// Domain of the returned cookie has a leading dot if it is not host-only.
+func (j *Jar) AllCookies() []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	var cookies []*http.Cookie
+	for key, c := range j.entries {
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			delete(j.entries, key)
+			continue
+		}
+		cc := *c
+		cookies = append(cookies, &cc)
+	}
+	sort.Slice(cookies, func(i, k int) bool {
+		if cookies[i].Domain != cookies[k].Domain {
+			return cookies[i].Domain < cookies[k].Domain
+		}
+		if cookies[i].Path != cookies[k].Path {
+			return cookies[i].Path < cookies[k].Path
+		}
+		return cookies[i].Name < cookies[k].Name
+	})
+	return cookies
+}
+
+type jsonCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain"`
+	Path     string `json:"path"`
+	Expires  int64  `json:"expires,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	HttpOnly bool   `json:"http_only,omitempty"`
+}
+
+// Save writes the cookies in the jar to w in format f.
+func (j *Jar) Save(w io.Writer, f CookieFormat) error {
+	cookies := j.AllCookies()
+	switch f {
+	case CookieFormatJSON:
+		jcs := make([]jsonCookie, 0, len(cookies))
+		for _, c := range cookies {
+			var expires int64
+			if !c.Expires.IsZero() {
+				expires = c.Expires.Unix()
+			}
+			jcs = append(jcs, jsonCookie{
+				Name:     c.Name,
+				Value:    c.Value,
+				Domain:   c.Domain,
+				Path:     c.Path,
+				Expires:  expires,
+				Secure:   c.Secure,
+				HttpOnly: c.HttpOnly,
+			})
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(jcs)
+	case CookieFormatNetscape:
+		bw := bufio.NewWriter(w)
+		fmt.Fprintln(bw, "# Netscape HTTP Cookie File")
+		for _, c := range cookies {
+			domain := c.Domain
+			if c.HttpOnly {
+				domain = "#HttpOnly_" + domain
+			}
+			var expires int64
+			if !c.Expires.IsZero() {
+				expires = c.Expires.Unix()
+			}
+			fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+				domain, netscapeBool(strings.HasPrefix(c.Domain, ".")), c.Path,
+				netscapeBool(c.Secure), expires, c.Name, c.Value)
+		}
+		return bw.Flush()
+	}
+	return fmt.Errorf("unknown cookie format: %d", f)
+}
+
+// Load reads the cookies in format f from r and sets them to the jar.
+func (j *Jar) Load(r io.Reader, f CookieFormat) error {
+	var cookies []*http.Cookie
+	switch f {
+	case CookieFormatJSON:
+		var jcs []jsonCookie
+		if err := json.NewDecoder(r).Decode(&jcs); err != nil {
+			return err
+		}
+		for _, jc := range jcs {
+			c := &http.Cookie{
+				Name:     jc.Name,
+				Value:    jc.Value,
+				Domain:   jc.Domain,
+				Path:     jc.Path,
+				Secure:   jc.Secure,
+				HttpOnly: jc.HttpOnly,
+			}
+			if jc.Expires > 0 {
+				c.Expires = time.Unix(jc.Expires, 0)
+			}
+			cookies = append(cookies, c)
+		}
+	case CookieFormatNetscape:
+		var err error
+		cookies, err = ParseNetscapeCookies(r)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown cookie format: %d", f)
+	}
+
+	for _, c := range cookies {
+		if c.Domain == "" {
+			continue
+		}
+		scheme := "http"
+		if c.Secure {
+			scheme = "https"
+		}
+		u := &url.URL{Scheme: scheme, Host: strings.TrimPrefix(c.Domain, "."), Path: c.Path}
+		if !strings.HasPrefix(c.Domain, ".") {
+			// Host-only cookie.
+			c.Domain = ""
+		}
+		j.SetCookies(u, []*http.Cookie{c})
+	}
+	return nil
+}
+
+// SaveFile writes the cookies in the jar to the file of name in format f.
+// The file is created with permission 0600 because it contains the session.
+func (j *Jar) SaveFile(name string, f CookieFormat) error {
+	fp, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := j.Save(fp, f); err != nil {
+		fp.Close()
+		return err
+	}
+	return fp.Close()
+}
+
+// LoadFile reads the cookies in format f from the file of name.
+func (j *Jar) LoadFile(name string, f CookieFormat) error {
+	fp, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	return j.Load(fp, f)
+}
+
+// ParseNetscapeCookies parses the cookies in the Netscape cookies.txt format.
+// Domain of the returned cookie has a leading dot if it is not host-only.
+func ParseNetscapeCookies(r io.Reader) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimRight(s.Text(), "\r")
+		httpOnly := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+			httpOnly = true
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("cookies.txt: line %d: want 7 fields but %d", n, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cookies.txt: line %d: %v", n, err)
+		}
+
+		domain := strings.ToLower(fields[0])
+		if strings.EqualFold(fields[1], "TRUE") {
+			domain = "." + strings.TrimPrefix(domain, ".")
+		} else {
+			domain = strings.TrimPrefix(domain, ".")
+		}
+		c := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Domain:   domain,
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if expires > 0 {
+			c.Expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, c)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return cookies, nil
+}
+
+// SaveCookies writes the cookies of c to the file of name in format f.
+// The Jar of c must be *Jar.
+func (c *Client) SaveCookies(name string, f CookieFormat) error {
+	j, ok := c.Jar.(*Jar)
+	if !ok {
+		return errors.New("cookie jar does not support saving")
+	}
+	return j.SaveFile(name, f)
+}
+
+// LoadCookies reads the cookies from the file of name in format f to c
+// and sets UserSession if the file has the session.
+// The Jar of c must be *Jar.
+func (c *Client) LoadCookies(name string, f CookieFormat) error {
+	j, ok := c.Jar.(*Jar)
+	if !ok {
+		return errors.New("cookie jar does not support loading")
+	}
+	if err := j.LoadFile(name, f); err != nil {
+		return err
+	}
+	for _, cookie := range j.AllCookies() {
+		if cookie.Name == "user_session" && domainMatch(strings.TrimPrefix(cookie.Domain, "."), "nicovideo.jp") {
+			c.setUserSession(cookie.Value)
+		}
+	}
+	return nil
+}
+
+// cookieValue returns the value of the cookie of name sent to u by jar.
+func cookieValue(jar http.CookieJar, u *url.URL, name string) (string, bool) {
+	if jar == nil {
+		return "", false
+	}
+	for _, c := range jar.Cookies(u) {
+		if c.Name == name {
+			return c.Value, true
+		}
+	}
+	return "", false
+}
+
+// replace sets value to the cookies of name sent to host.
+// It reports whether any cookie is replaced.
+func (j *Jar) replace(host, name, value string) bool {
+	replaced := false
+	for _, c := range j.AllCookies() {
+		domain := strings.TrimPrefix(c.Domain, ".")
+		if c.Name != name || !(host == domain || strings.HasPrefix(c.Domain, ".") && domainMatch(host, domain)) {
+			continue
+		}
+		u := &url.URL{Scheme: "https", Host: domain, Path: c.Path}
+		nc := &http.Cookie{Name: c.Name, Value: value, Path: c.Path, Expires: c.Expires, Secure: c.Secure, HttpOnly: c.HttpOnly}
+		if strings.HasPrefix(c.Domain, ".") {
+			nc.Domain = c.Domain
+		}
+		j.SetCookies(u, []*http.Cookie{nc})
+		replaced = true
+	}
+	return replaced
+}
+
+func domainMatch(host, domain string) bool {
+	if host == domain {
+		return true
+	}
+	if net.ParseIP(host) != nil {
+		return false
+	}
+	return strings.HasSuffix(host, "."+domain)
+}
+
+func defaultCookiePath(p string) string {
+	i := strings.LastIndex(p, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return p[:i]
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -0,0 +1,191 @@
+package nico
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJar_SaveLoad(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	for _, f := range []CookieFormat{CookieFormatJSON, CookieFormatNetscape} {
+		j := NewJar()
+		j.SetCookies(&url.URL{Scheme: "http", Host: "live.nicovideo.jp", Path: "/"}, []*http.Cookie{
+			{Name: "user_session", Value: "foobarbaz", Domain: ".nicovideo.jp", Path: "/", Expires: expires, HttpOnly: true},
+			{Name: "nicosid", Value: "123", Path: "/"},
+			{Name: "expired", Value: "1", Path: "/", Expires: time.Now().Add(-time.Hour)},
+			{Name: "other", Value: "1", Domain: ".example.com", Path: "/"},
+		})
+
+		var buf bytes.Buffer
+		if err := j.Save(&buf, f); err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+		lj := NewJar()
+		if err := lj.Load(&buf, f); err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+
+		cookies := lj.AllCookies()
+		if len(cookies) != 2 {
+			t.Fatalf("want %d but %d", 2, len(cookies))
+		}
+		if cookies[0].Domain != ".nicovideo.jp" || cookies[0].Name != "user_session" || cookies[0].Value != "foobarbaz" {
+			t.Fatalf("unexpected cookie: %v", cookies[0])
+		}
+		if !cookies[0].Expires.Equal(expires) {
+			t.Fatalf("want %v but %v", expires, cookies[0].Expires)
+		}
+		if !cookies[0].HttpOnly {
+			t.Fatalf("should be HttpOnly")
+		}
+		if cookies[1].Domain != "live.nicovideo.jp" || cookies[1].Name != "nicosid" {
+			t.Fatalf("unexpected cookie: %v", cookies[1])
+		}
+
+		if !hasCookie(lj, &url.URL{Scheme: "http", Host: "com.nicovideo.jp", Path: "/"}, "user_session") {
+			t.Fatalf("user_session should be sent to com.nicovideo.jp")
+		}
+		if hasCookie(lj, &url.URL{Scheme: "http", Host: "com.nicovideo.jp", Path: "/"}, "nicosid") {
+			t.Fatalf("host-only cookie should not be sent to com.nicovideo.jp")
+		}
+	}
+}
+
+func TestParseNetscapeCookies(t *testing.T) {
+	cookies, err := ParseNetscapeCookies(strings.NewReader("# Netscape HTTP Cookie File\n\n" +
+		"#HttpOnly_.nicovideo.jp\tTRUE\t/\tFALSE\t0\tuser_session\tfoobarbaz\n" +
+		"live.nicovideo.jp\tFALSE\t/\tTRUE\t2000000000\tnicosid\t123\n"))
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if len(cookies) != 2 {
+		t.Fatalf("want %d but %d", 2, len(cookies))
+	}
+	if c := cookies[0]; c.Domain != ".nicovideo.jp" || !c.HttpOnly || !c.Expires.IsZero() || c.Value != "foobarbaz" {
+		t.Fatalf("unexpected cookie: %v", c)
+	}
+	if c := cookies[1]; c.Domain != "live.nicovideo.jp" || !c.Secure || c.Expires.Unix() != 2000000000 {
+		t.Fatalf("unexpected cookie: %v", c)
+	}
+
+	if _, err := ParseNetscapeCookies(strings.NewReader("foo\tbar\n")); err == nil {
+		t.Fatalf("should be fail: %v", err)
+	}
+}
+
+func TestClient_SaveLoadCookies(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "cookies.txt")
+
+	c := NewClient()
+	c.Jar.SetCookies(&url.URL{Scheme: "https", Host: "secure.nicovideo.jp", Path: "/"}, []*http.Cookie{
+		{Name: "user_session", Value: "foobarbaz", Domain: ".nicovideo.jp", Path: "/"},
+	})
+	if err := c.SaveCookies(name, CookieFormatNetscape); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	lc := NewClient()
+	if err := lc.LoadCookies(name, CookieFormatNetscape); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if lc.UserSession != "foobarbaz" {
+		t.Fatalf("want %q but %q", "foobarbaz", lc.UserSession)
+	}
+
+	hc := &Client{}
+	if err := hc.SaveCookies(name, CookieFormatJSON); err == nil {
+		t.Fatalf("should be fail: %v", err)
+	}
+}
+
+func TestClient_JarSession(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "nicosid", Value: "123", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "user_session", Value: "foobarbaz", Path: "/"})
+			http.Redirect(w, r, "http://example.com", http.StatusFound)
+		case "/api/getpostkey":
+			if cs := r.Cookies(); len(cs) != 2 {
+				t.Errorf("want %d cookies but %v", 2, cs)
+			}
+			if c, err := r.Cookie("nicosid"); err != nil || c.Value != "123" {
+				t.Errorf("want %q but %v", "123", c)
+			}
+			w.Write([]byte("postkey=foo"))
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithLoginURL(ts.URL+"/login"), WithLiveBaseURL(ts.URL))
+	if _, err := c.Login(context.Background(), "foo@foo.com", "bar"); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if _, err := c.GetPostkey(context.Background(), 1); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+}
+
+func TestClient_AuthCookie(t *testing.T) {
+	cookies := map[string]string{}
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		cookies[r.URL.Path] = r.Header.Get("Cookie")
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "user_session", Value: "jar", Path: "/"})
+			http.Redirect(w, r, "http://example.com", http.StatusFound)
+		case "/api/getpostkey":
+			w.Write([]byte("postkey=foo"))
+		case "/api/v1/user.info":
+			w.Write([]byte(`<nicovideo_user_response status="ok"></nicovideo_user_response>`))
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	c := NewClient(WithLiveBaseURL(ts.URL), WithCEBaseURL(ts.URL), WithLoginURL(ts.URL+"/login"))
+	c.UserSession = "explicit"
+	if _, err := c.GetNicovideoUserResponse(ctx, 1); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if _, err := c.Login(ctx, "foo@foo.com", "bar"); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	// The explicit session replaces the one in the jar instead of being sent together.
+	c.UserSession = "explicit"
+	if _, err := c.GetPostkey(ctx, 1); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	// UserSession is sent only to the endpoints that require login.
+	want := map[string]string{
+		"/api/v1/user.info": "",
+		"/login":            "",
+		"/api/getpostkey":   "user_session=explicit",
+	}
+	if !reflect.DeepEqual(cookies, want) {
+		t.Fatalf("want %v but %v", want, cookies)
+	}
+	u, _ := url.Parse(ts.URL)
+	if v, _ := cookieValue(c.Jar, u, "user_session"); v != "explicit" {
+		t.Fatalf("want %q but %q", "explicit", v)
+	}
+}
+
+func hasCookie(jar http.CookieJar, u *url.URL, name string) bool {
+	_, ok := cookieValue(jar, u, name)
+	return ok
+}
@@ -0,0 +1,91 @@
+package nico
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// Endpoint is a name of the niconico API called by Client.
+type Endpoint string
+
+// Endpoints called by Client.
+const (
+	EndpointLogin              Endpoint = "login"
+	EndpointLoginMFA           Endpoint = "login_mfa"
+	EndpointLogout             Endpoint = "logout"
+	EndpointUsersMe            Endpoint = "users/me"
+	EndpointGetPostkey         Endpoint = "getpostkey"
+	EndpointGetPlayerStatus    Endpoint = "getplayerstatus"
+	EndpointFollowCommunity    Endpoint = "follow_community"
+	EndpointLeaveCommunityForm Endpoint = "leave_community_form"
+	EndpointLeaveCommunity     Endpoint = "leave_community"
+	EndpointWatch              Endpoint = "watch"
+	EndpointUserInfo           Endpoint = "user.info"
+)
+
+// Errors returned by Client.
+var (
+	ErrNotLoggedIn            = errors.New("not logged in")
+	ErrLoginFailed            = errors.New("login failed")
+	ErrOTPRequired            = errors.New("one-time password required")
+	ErrInvalidOTP             = errors.New("invalid one-time password")
+	ErrPostkeyEmpty           = errors.New("postkey is empty")
+	ErrCommunityNotFound      = errors.New("community not found")
+	ErrCommunityFollowFailed  = errors.New("community follow failed")
+	ErrCommunityLeaveFailed   = errors.New("community leave failed")
+	ErrLiveIDNotFound         = errors.New("live id not found")
+	ErrSeatsFull              = errors.New("seats are full")
+	ErrRequireCommunityMember = errors.New("require community member")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrNoAccountAvailable     = errors.New("no account available")
+	ErrContentsNotFound       = errors.New("contents not found")
+	ErrLiveClosed             = errors.New("live is closed")
+	ErrLiveNotStarted         = errors.New("live is not started")
+	ErrLiveNotFound           = errors.New("live not found")
+	ErrLiveDeleted            = errors.New("live is deleted")
+	ErrNoPermission           = errors.New("no permission")
+	ErrTimeshiftUnavailable   = errors.New("timeshift is unavailable")
+	ErrMaintenance            = errors.New("under maintenance")
+	ErrReconnectFailed        = errors.New("reconnect failed")
+	ErrStalled                = errors.New("comment stream stalled")
+	ErrCommentOverflow        = errors.New("comment buffer overflow")
+	ErrEventHandlerRequired   = errors.New("comment handler does not handle events")
+)
+
+// maxErrorBodySize is the maximum size of Body of APIError.
+const maxErrorBodySize = 512
+
+// APIError is an error to return if the status code of the response is unexpected.
+type APIError struct {
+	Endpoint   Endpoint
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func newAPIError(ep Endpoint, resp *http.Response) *APIError {
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	return &APIError{
+		Endpoint:   ep,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       string(b),
+	}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Endpoint, e.Status)
+}
+
+// ChatResultError is an error of the rejected comment returned by ChatResult.Err.
+type ChatResultError struct {
+	Thread int64
+	Status int64
+}
+
+func (e *ChatResultError) Error() string {
+	return fmt.Sprintf("comment rejected: thread %d: status %d", e.Thread, e.Status)
+}
@@ -0,0 +1,148 @@
+package nico
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(strings.Repeat("a", maxErrorBodySize+1)))
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithLiveBaseURL(ts.URL))
+	_, err := c.GetPlayerStatus(context.Background(), "lv123456789")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("should be *APIError: %T", err)
+	}
+	if apiErr.Endpoint != EndpointGetPlayerStatus {
+		t.Fatalf("want %q but %q", EndpointGetPlayerStatus, apiErr.Endpoint)
+	}
+	if apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("want %d but %d", http.StatusServiceUnavailable, apiErr.StatusCode)
+	}
+	if len(apiErr.Body) != maxErrorBodySize {
+		t.Fatalf("want %d but %d", maxErrorBodySize, len(apiErr.Body))
+	}
+	if got, want := err.Error(), "getplayerstatus: 503 Service Unavailable"; got != want {
+		t.Fatalf("want %q but %q", want, got)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.Redirect(w, r, "http://example.com/login", http.StatusFound)
+		case "/api/getpostkey":
+			w.Write([]byte("postkey="))
+		case "/motion/co1234567":
+			http.Redirect(w, r, "http://example.com/motion/co1234567", http.StatusFound)
+		case "/watch/lv123456789":
+			w.Write([]byte(`<div class="shosai"></div>`))
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithLoginURL(ts.URL+"/login"), WithLiveBaseURL(ts.URL), WithCommunityBaseURL(ts.URL))
+	ctx := context.Background()
+	if _, err := c.Login(ctx, "foo@foo.com", "bar"); !errors.Is(err, ErrLoginFailed) {
+		t.Fatalf("want %v but %v", ErrLoginFailed, err)
+	}
+	if _, err := c.GetPostkey(ctx, 1); !errors.Is(err, ErrPostkeyEmpty) {
+		t.Fatalf("want %v but %v", ErrPostkeyEmpty, err)
+	}
+	if err := c.FollowCommunity(ctx, "co1234567"); !errors.Is(err, ErrCommunityFollowFailed) {
+		t.Fatalf("want %v but %v", ErrCommunityFollowFailed, err)
+	}
+	if _, err := c.GetCommunityIDFromLiveID(ctx, "lv123456789"); !errors.Is(err, ErrCommunityNotFound) {
+		t.Fatalf("want %v but %v", ErrCommunityNotFound, err)
+	}
+	if _, err := FindLiveID("http://live.nicovideo.jp/"); !errors.Is(err, ErrLiveIDNotFound) {
+		t.Fatalf("want %v but %v", ErrLiveIDNotFound, err)
+	}
+}
+
+func TestPlayerStatusError_Is(t *testing.T) {
+	tests := []struct {
+		code   string
+		target error
+	}{
+		{PlayerStatusErrorCodeFull, ErrSeatsFull},
+		{PlayerStatusErrorCodeNotlogin, ErrNotLoggedIn},
+		{PlayerStatusErrorCodeRequireCommunityMember, ErrRequireCommunityMember},
+		{PlayerStatusErrorCodeClosed, ErrLiveClosed},
+		{PlayerStatusErrorCodeComingsoon, ErrLiveNotStarted},
+		{PlayerStatusErrorCodeNotfound, ErrLiveNotFound},
+		{PlayerStatusErrorCodeDeletedbyuser, ErrLiveDeleted},
+		{PlayerStatusErrorCodeDeletedbyvisor, ErrLiveDeleted},
+		{PlayerStatusErrorCodeNoauth, ErrNoPermission},
+		{PlayerStatusErrorCodeTimeshiftTicketExhaust, ErrTimeshiftUnavailable},
+		{PlayerStatusErrorCodeUsertimeshift, ErrTimeshiftUnavailable},
+		{PlayerStatusErrorCodeMaintenance, ErrMaintenance},
+	}
+	for _, tt := range tests {
+		var err error = PlayerStatusError{Status: "fail", Code: tt.code}
+		if !errors.Is(err, tt.target) {
+			t.Fatalf("%q should be %v", tt.code, tt.target)
+		}
+		if errors.Is(err, ErrLoginFailed) {
+			t.Fatalf("%q should not be %v", tt.code, ErrLoginFailed)
+		}
+	}
+}
+
+func TestPlayerStatusError_Kind(t *testing.T) {
+	tests := []struct {
+		code      string
+		kind      PlayerStatusErrorKind
+		temporary bool
+	}{
+		{PlayerStatusErrorCodeFull, PlayerStatusErrorRetryable, true},
+		{PlayerStatusErrorCodeComingsoon, PlayerStatusErrorRetryable, true},
+		{PlayerStatusErrorCodeMaintenance, PlayerStatusErrorRetryable, true},
+		{PlayerStatusErrorCodeUnknown, PlayerStatusErrorRetryable, true},
+		{PlayerStatusErrorCodeNotlogin, PlayerStatusErrorAuth, false},
+		{PlayerStatusErrorCodeNoauth, PlayerStatusErrorAuth, false},
+		{PlayerStatusErrorCodeRequireCommunityMember, PlayerStatusErrorAuth, false},
+		{PlayerStatusErrorCodeClosed, PlayerStatusErrorFatal, false},
+		{PlayerStatusErrorCodeNotfound, PlayerStatusErrorFatal, false},
+		{PlayerStatusErrorCodeDeletedbyuser, PlayerStatusErrorFatal, false},
+		{PlayerStatusErrorCodeDeletedbyvisor, PlayerStatusErrorFatal, false},
+		{PlayerStatusErrorCodeTimeshiftTicketExhaust, PlayerStatusErrorFatal, false},
+		{PlayerStatusErrorCodeUsertimeshift, PlayerStatusErrorFatal, false},
+		{"undefined_code", PlayerStatusErrorFatal, false},
+	}
+	for _, tt := range tests {
+		err := PlayerStatusError{Status: "fail", Code: tt.code}
+		if got := err.Kind(); got != tt.kind {
+			t.Fatalf("%q: want %v but %v", tt.code, tt.kind, got)
+		}
+		if got := err.Temporary(); got != tt.temporary {
+			t.Fatalf("%q: want %v but %v", tt.code, tt.temporary, got)
+		}
+	}
+
+	var err error = PlayerStatusError{Status: "fail", Code: PlayerStatusErrorCodeUnknown}
+	if errors.Is(err, nil) {
+		t.Fatalf("%q should not be nil", PlayerStatusErrorCodeUnknown)
+	}
+}
+
+func TestUserInfoError_Is(t *testing.T) {
+	var err error = UserInfoError{Status: "fail", Code: UserInfoErrorCodeNotFound}
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("should be %v", ErrUserNotFound)
+	}
+	err = UserInfoError{Status: "fail", Code: "INTERNAL"}
+	if errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("should not be %v", ErrUserNotFound)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/178inaba/nico
+
+go 1.24.0
+
+require github.com/PuerkitoBio/goquery v1.10.3
+
+require (
+	github.com/andybalholm/cascadia v1.3.3 // indirect
+	golang.org/x/net v0.50.0 // indirect
+)
@@ -1,9 +1,6 @@
 package nico
 
-import (
-	"errors"
-	"regexp"
-)
+import "regexp"
 
 var liveIDRE = regexp.MustCompile(`lv\d+`)
 
@@ -11,7 +8,7 @@ var liveIDRE = regexp.MustCompile(`lv\d+`)
 func FindLiveID(s string) (string, error) {
 	liveID := liveIDRE.Copy().FindString(s)
 	if liveID == "" {
-		return "", errors.New("live id not found")
+		return "", ErrLiveIDNotFound
 	}
 	return liveID, nil
 }
@@ -0,0 +1,428 @@
+// Package sqlite provides a minimal read-only reader of SQLite database files.
+// It supports only scanning all rows of a table, which is enough
+// to read the cookie store of the browsers.
+package sqlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+)
+
+const headerMagic = "SQLite format 3\x00"
+
+// Page types of B-tree.
+const (
+	pageInteriorTable = 0x05
+	pageLeafTable     = 0x0d
+)
+
+// ErrNotDatabase is returned when the file is not an SQLite database.
+var ErrNotDatabase = errors.New("sqlite: file is not a database")
+
+// DB is a read-only SQLite database loaded in memory.
+type DB struct {
+	data       []byte
+	pageSize   int
+	usableSize int
+	pages      map[int][]byte
+}
+
+// Row is a row of a table. The value is nil, int64, float64, string or []byte.
+type Row map[string]interface{}
+
+// Open reads the database file of name.
+// The committed pages in the write-ahead log of name + "-wal" are applied if it exists.
+func Open(name string) (*DB, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	db, err := Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	wal, err := ioutil.ReadFile(name + "-wal")
+	if err == nil {
+		db.applyWAL(wal)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Parse parses the content of a database file.
+func Parse(b []byte) (*DB, error) {
+	if len(b) < 100 || string(b[:16]) != headerMagic {
+		return nil, ErrNotDatabase
+	}
+	pageSize := int(binary.BigEndian.Uint16(b[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, ErrNotDatabase
+	}
+	// The usable size must be at least 480.
+	usableSize := pageSize - int(b[20])
+	if usableSize < 480 {
+		return nil, ErrNotDatabase
+	}
+	return &DB{
+		data:       b,
+		pageSize:   pageSize,
+		usableSize: usableSize,
+		pages:      map[int][]byte{},
+	}, nil
+}
+
+// applyWAL overlays the pages of the committed frames in the write-ahead log.
+func (db *DB) applyWAL(wal []byte) {
+	const walHeaderSize, frameHeaderSize = 32, 24
+	if len(wal) < walHeaderSize || int(binary.BigEndian.Uint32(wal[8:12])) != db.pageSize {
+		return
+	}
+	salt := wal[16:24]
+	frameSize := frameHeaderSize + db.pageSize
+
+	pending := map[int][]byte{}
+	for off := walHeaderSize; off+frameSize <= len(wal); off += frameSize {
+		fh := wal[off : off+frameHeaderSize]
+		if !bytes.Equal(fh[8:16], salt) {
+			break
+		}
+		pgno := int(binary.BigEndian.Uint32(fh[0:4]))
+		pending[pgno] = wal[off+frameHeaderSize : off+frameSize]
+		if binary.BigEndian.Uint32(fh[4:8]) != 0 {
+			// Commit frame.
+			for n, p := range pending {
+				db.pages[n] = p
+			}
+			pending = map[int][]byte{}
+		}
+	}
+}
+
+func (db *DB) page(n int) ([]byte, error) {
+	if p, ok := db.pages[n]; ok {
+		return p, nil
+	}
+	off := (n - 1) * db.pageSize
+	if n < 1 || off+db.pageSize > len(db.data) {
+		return nil, fmt.Errorf("sqlite: page %d out of range", n)
+	}
+	return db.data[off : off+db.pageSize], nil
+}
+
+// Rows returns all rows of the table of name.
+func (db *DB) Rows(name string) ([]Row, error) {
+	var rootpage int
+	var columns []column
+	err := db.scan(1, func(rowid int64, values []interface{}) error {
+		if len(values) < 5 || values[0] != "table" || !strings.EqualFold(fmt.Sprint(values[1]), name) {
+			return nil
+		}
+		rp, _ := values[3].(int64)
+		rootpage = int(rp)
+		sql, _ := values[4].(string)
+		columns = parseColumns(sql)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rootpage == 0 {
+		return nil, fmt.Errorf("sqlite: no such table: %s", name)
+	}
+
+	var rows []Row
+	err = db.scan(rootpage, func(rowid int64, values []interface{}) error {
+		row := Row{}
+		for i, col := range columns {
+			var v interface{}
+			if i < len(values) {
+				v = values[i]
+			}
+			if col.rowid && v == nil {
+				v = rowid
+			}
+			row[col.name] = v
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// scan calls fn with every record of the table B-tree of the root page.
+func (db *DB) scan(root int, fn func(rowid int64, values []interface{}) error) error {
+	return db.scanPage(root, 0, map[int]bool{}, fn)
+}
+
+func (db *DB) scanPage(n, depth int, visited map[int]bool, fn func(rowid int64, values []interface{}) error) error {
+	if depth > 64 {
+		return errors.New("sqlite: B-tree is too deep")
+	}
+	// A corrupted B-tree may refer to a page twice.
+	if visited[n] {
+		return fmt.Errorf("sqlite: page %d is referred twice", n)
+	}
+	visited[n] = true
+	p, err := db.page(n)
+	if err != nil {
+		return err
+	}
+	hdr := 0
+	if n == 1 {
+		hdr = 100
+	}
+	if len(p) < hdr+12 {
+		return fmt.Errorf("sqlite: page %d is corrupted", n)
+	}
+
+	typ := p[hdr]
+	ncells := int(binary.BigEndian.Uint16(p[hdr+3 : hdr+5]))
+	cellPtrs := hdr + 8
+	if typ == pageInteriorTable {
+		cellPtrs = hdr + 12
+	}
+	if cellPtrs+ncells*2 > len(p) {
+		return fmt.Errorf("sqlite: page %d is corrupted", n)
+	}
+
+	switch typ {
+	case pageInteriorTable:
+		for i := 0; i < ncells; i++ {
+			off := int(binary.BigEndian.Uint16(p[cellPtrs+i*2:]))
+			if off+4 > len(p) {
+				return fmt.Errorf("sqlite: page %d is corrupted", n)
+			}
+			if err := db.scanPage(int(binary.BigEndian.Uint32(p[off:])), depth+1, visited, fn); err != nil {
+				return err
+			}
+		}
+		return db.scanPage(int(binary.BigEndian.Uint32(p[hdr+8:])), depth+1, visited, fn)
+	case pageLeafTable:
+		for i := 0; i < ncells; i++ {
+			off := int(binary.BigEndian.Uint16(p[cellPtrs+i*2:]))
+			if off < cellPtrs+ncells*2 || off >= len(p) {
+				return fmt.Errorf("sqlite: page %d is corrupted", n)
+			}
+			rowid, payload, err := db.leafCell(p, off)
+			if err != nil {
+				return err
+			}
+			values, err := parseRecord(payload)
+			if err != nil {
+				return err
+			}
+			if err := fn(rowid, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("sqlite: page %d is not a table page: %#x", n, typ)
+}
+
+// leafCell returns the rowid and the whole payload of the cell at off in the leaf page p.
+func (db *DB) leafCell(p []byte, off int) (int64, []byte, error) {
+	// The payload cannot be larger than the database.
+	size, n := varint(p[off:])
+	if n == 0 || size > uint64(len(db.data)+len(db.pages)*db.pageSize) {
+		return 0, nil, errors.New("sqlite: corrupted cell")
+	}
+	off += n
+	rowid, n := varint(p[off:])
+	if n == 0 {
+		return 0, nil, errors.New("sqlite: corrupted cell")
+	}
+	off += n
+
+	u := db.usableSize
+	local := int(size)
+	if x := u - 35; local > x {
+		m := (u-12)*32/255 - 23
+		k := m + (int(size)-m)%(u-4)
+		if k <= x {
+			local = k
+		} else {
+			local = m
+		}
+	}
+	if off+local > len(p) {
+		return 0, nil, errors.New("sqlite: corrupted cell")
+	}
+	payload := append([]byte(nil), p[off:off+local]...)
+	if local == int(size) {
+		return int64(rowid), payload, nil
+	}
+
+	if off+local+4 > len(p) {
+		return 0, nil, errors.New("sqlite: corrupted cell")
+	}
+	next := int(binary.BigEndian.Uint32(p[off+local:]))
+	for len(payload) < int(size) && next != 0 {
+		op, err := db.page(next)
+		if err != nil {
+			return 0, nil, err
+		}
+		next = int(binary.BigEndian.Uint32(op))
+		chunk := op[4:u]
+		if rest := int(size) - len(payload); len(chunk) > rest {
+			chunk = chunk[:rest]
+		}
+		payload = append(payload, chunk...)
+	}
+	if len(payload) != int(size) {
+		return 0, nil, errors.New("sqlite: truncated overflow pages")
+	}
+	return int64(rowid), payload, nil
+}
+
+func parseRecord(b []byte) ([]interface{}, error) {
+	hsize, n := varint(b)
+	if n == 0 || hsize > uint64(len(b)) {
+		return nil, errors.New("sqlite: corrupted record")
+	}
+	var types []uint64
+	for off := n; off < int(hsize); {
+		t, n := varint(b[off:int(hsize)])
+		if n == 0 {
+			return nil, errors.New("sqlite: corrupted record")
+		}
+		types = append(types, t)
+		off += n
+	}
+
+	values := make([]interface{}, 0, len(types))
+	body := b[hsize:]
+	for _, t := range types {
+		size := serialSize(t)
+		if size < 0 || size > len(body) {
+			return nil, errors.New("sqlite: corrupted record")
+		}
+		v := body[:size]
+		body = body[size:]
+		switch {
+		case t == 0:
+			values = append(values, nil)
+		case t <= 6:
+			values = append(values, bigEndianInt(v))
+		case t == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(v)))
+		case t == 8:
+			values = append(values, int64(0))
+		case t == 9:
+			values = append(values, int64(1))
+		case t >= 12 && t%2 == 0:
+			values = append(values, append([]byte(nil), v...))
+		case t >= 13:
+			values = append(values, string(v))
+		default:
+			return nil, fmt.Errorf("sqlite: unknown serial type %d", t)
+		}
+	}
+	return values, nil
+}
+
+func serialSize(t uint64) int {
+	switch {
+	case t <= 4:
+		return int(t)
+	case t == 5:
+		return 6
+	case t == 6, t == 7:
+		return 8
+	case t < 12:
+		return 0
+	}
+	return int((t - 12) / 2)
+}
+
+func bigEndianInt(b []byte) int64 {
+	var v int64
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		v = -1
+	}
+	for _, c := range b {
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+// varint decodes the variable-length integer of SQLite.
+// It returns 0 as n if b is too short.
+func varint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+type column struct {
+	name  string
+	rowid bool
+}
+
+// parseColumns parses the column names from the CREATE TABLE statement.
+func parseColumns(sql string) []column {
+	start, end := strings.Index(sql, "("), strings.LastIndex(sql, ")")
+	if start < 0 || end <= start {
+		return nil
+	}
+
+	var defs []string
+	depth, last := 0, start+1
+	for i := start + 1; i < end; i++ {
+		switch sql[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				defs = append(defs, sql[last:i])
+				last = i + 1
+			}
+		}
+	}
+	defs = append(defs, sql[last:end])
+
+	var columns []column
+	for _, def := range defs {
+		fields := strings.Fields(def)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			continue
+		}
+		name := strings.Trim(fields[0], "\"`[]'")
+		upper := strings.ToUpper(strings.Join(fields[1:], " "))
+		columns = append(columns, column{
+			name:  name,
+			rowid: strings.HasPrefix(upper, "INTEGER PRIMARY KEY"),
+		})
+	}
+	return columns
+}
@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	db, err := Open("testdata/wal.sqlite")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	rows, err := db.Rows("t")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if len(rows) != 302 {
+		t.Fatalf("want %d but %d", 302, len(rows))
+	}
+
+	r := rows[0]
+	if r["id"] != int64(1) || r["name"] != "row1" || r["score"] != 0.5 || r["n"] != int64(-100000) {
+		t.Fatalf("unexpected row: %v", r)
+	}
+	if v, ok := r["value"].([]byte); !ok || string(v) != "\x00\x01" {
+		t.Fatalf("unexpected value: %v", r["value"])
+	}
+	if r := rows[300]; r["name"] != "long" || r["value"] != strings.Repeat("x", 5000) || r["score"] != nil {
+		t.Fatalf("unexpected row: %v", r["name"])
+	}
+	if r := rows[301]; r["name"] != "wal" || r["n"] != int64(1<<40) {
+		t.Fatalf("unexpected row: %v", r)
+	}
+}
+
+func TestParse(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/wal.sqlite")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	db, err := Parse(b)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	rows, err := db.Rows("t")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if len(rows) != 301 {
+		t.Fatalf("want %d but %d", 301, len(rows))
+	}
+	if _, err := db.Rows("nothing"); err == nil {
+		t.Fatalf("should be fail: %v", err)
+	}
+
+	if _, err := Parse([]byte("foo")); err != ErrNotDatabase {
+		t.Fatalf("want %v but %v", ErrNotDatabase, err)
+	}
+}
+
+func TestVarint(t *testing.T) {
+	tests := []struct {
+		in []byte
+		v  uint64
+		n  int
+	}{
+		{[]byte{0x00}, 0, 1},
+		{[]byte{0x7f}, 127, 1},
+		{[]byte{0x81, 0x00}, 128, 2},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 1<<64 - 1, 9},
+		{[]byte{0x81}, 0, 0},
+	}
+	for _, tt := range tests {
+		v, n := varint(tt.in)
+		if v != tt.v || n != tt.n {
+			t.Fatalf("%x: want %d, %d but %d, %d", tt.in, tt.v, tt.n, v, n)
+		}
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	cols := parseColumns(`CREATE TABLE "t" (id INTEGER PRIMARY KEY, "name" TEXT, v DECIMAL(10, 2), PRIMARY KEY (name))`)
+	if len(cols) != 3 {
+		t.Fatalf("want %d but %d", 3, len(cols))
+	}
+	if cols[0].name != "id" || !cols[0].rowid {
+		t.Fatalf("unexpected column: %v", cols[0])
+	}
+	if cols[1].name != "name" || cols[1].rowid {
+		t.Fatalf("unexpected column: %v", cols[1])
+	}
+	if cols[2].name != "v" {
+		t.Fatalf("unexpected column: %v", cols[2])
+	}
+}
+
+func TestParse_CorruptedPage(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/wal.sqlite")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	db, err := Parse(b)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	// Point the first cell of every leaf page out of the page.
+	for off := 0; off+db.pageSize <= len(b); off += db.pageSize {
+		hdr := off
+		if off == 0 {
+			hdr = 100
+		}
+		if b[hdr] == pageLeafTable {
+			b[hdr+8], b[hdr+9] = 0xff, 0xff
+		}
+	}
+	if _, err := db.Rows("t"); err == nil {
+		t.Fatalf("should be fail: %v", err)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	b, err := ioutil.ReadFile("testdata/wal.sqlite")
+	if err != nil {
+		f.Fatalf("should not be fail: %v", err)
+	}
+	f.Add(b)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		db, err := Parse(b)
+		if err != nil {
+			return
+		}
+		db.Rows("t")
+	})
+}
@@ -0,0 +1,126 @@
+package nico
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxMFARedirects is the maximum number of redirects after the one-time password is submitted.
+const maxMFARedirects = 10
+
+// OTPProvider returns the one-time password of two-step verification.
+type OTPProvider func(ctx context.Context) (string, error)
+
+func isMFARedirect(u *url.URL) bool {
+	return strings.HasPrefix(path.Base(u.Path), "mfa")
+}
+
+// loginMFA submits the one-time password on the two-step verification page of u.
+func (c *Client) loginMFA(ctx context.Context, u *url.URL) (string, error) {
+	if c.otpProvider == nil {
+		return "", ErrOTPRequired
+	}
+
+	action, v, err := c.getMFAFormData(ctx, u)
+	if err != nil {
+		return "", err
+	}
+	otp, err := c.otpProvider(ctx)
+	if err != nil {
+		return "", err
+	}
+	v.Set("otp", otp)
+	if c.trustedDeviceName != "" {
+		v.Set("is_mfa_trusted_device", "true")
+		v.Set("device_name", c.trustedDeviceName)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, action.String(), strings.NewReader(v.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Referer", u.String())
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	for i := 0; ; i++ {
+		resp, err := c.do(withoutRedirect(req), EndpointLoginMFA)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+		if us := findUserSession(resp.Cookies()); us != "" {
+			return us, nil
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			// The verification page is shown again.
+			return "", ErrInvalidOTP
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect:
+		default:
+			return "", newAPIError(EndpointLoginMFA, resp)
+		}
+		loc, err := resp.Location()
+		if err != nil || i >= maxMFARedirects {
+			return "", ErrLoginFailed
+		}
+		if isMFARedirect(loc) {
+			return "", ErrInvalidOTP
+		}
+		req, err = c.newRequest(ctx, http.MethodGet, loc.String(), nil)
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// getMFAFormData gets the URL and the values of the form to submit the one-time password.
+func (c *Client) getMFAFormData(ctx context.Context, u *url.URL) (*url.URL, url.Values, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.do(req, EndpointLoginMFA)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, newAPIError(EndpointLoginMFA, resp)
+	}
+
+	doc, err := goquery.NewDocumentFromResponse(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	form := doc.Find("form").Has(`input[name="otp"]`).First()
+	if form.Length() == 0 {
+		return nil, nil, fmt.Errorf("%w: two-step verification form not found", ErrLoginFailed)
+	}
+	action, err := resp.Request.URL.Parse(form.AttrOr("action", ""))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v := url.Values{}
+	form.Find("input").Each(func(i int, s *goquery.Selection) {
+		name, ok := s.Attr("name")
+		if !ok || name == "otp" {
+			return
+		}
+		if typ := s.AttrOr("type", "text"); typ == "checkbox" || typ == "radio" {
+			if _, checked := s.Attr("checked"); !checked {
+				return
+			}
+		}
+		v.Set(name, s.AttrOr("value", ""))
+	})
+	return action, v, nil
+}
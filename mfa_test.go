@@ -0,0 +1,104 @@
+package nico
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newMFAServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/secure/login":
+			http.SetCookie(w, &http.Cookie{Name: "mfa_session", Value: "mfa", Path: "/"})
+			http.Redirect(w, r, "/mfa?site=niconico", http.StatusFound)
+		case "/mfa":
+			if c, err := r.Cookie("mfa_session"); err != nil || c.Value != "mfa" {
+				t.Errorf("mfa_session should be sent: %v", err)
+			}
+			if r.Method == http.MethodGet {
+				io.WriteString(w, `<form action="/mfa?site=niconico" method="post">
+<input type="hidden" name="csrf_token" value="csrf">
+<input type="text" name="otp">
+<input type="checkbox" name="is_mfa_trusted_device" value="true">
+<input type="submit" name="loginBtn" value="login">
+</form>`)
+				return
+			}
+			if got := r.FormValue("csrf_token"); got != "csrf" {
+				t.Errorf("want %q but %q", "csrf", got)
+			}
+			if r.FormValue("otp") != "123456" {
+				http.Redirect(w, r, "/mfa?site=niconico&error=1", http.StatusFound)
+				return
+			}
+			if r.FormValue("is_mfa_trusted_device") != "true" || r.FormValue("device_name") != "bot" {
+				t.Errorf("device should be trusted: %v", r.Form)
+			}
+			http.Redirect(w, r, "/redirector", http.StatusFound)
+		case "/redirector":
+			http.SetCookie(w, &http.Cookie{Name: "user_session", Value: "foobarbaz", Path: "/"})
+			http.Redirect(w, r, "/", http.StatusFound)
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+	}))
+}
+
+func TestLogin_MFA(t *testing.T) {
+	ts := newMFAServer(t)
+	defer ts.Close()
+
+	var called int
+	c := NewClient(
+		WithLoginURL(ts.URL+"/secure/login"),
+		WithOTPProvider(func(ctx context.Context) (string, error) {
+			called++
+			return "123456", nil
+		}),
+		WithTrustedDevice("bot"),
+	)
+	us, err := c.Login(context.Background(), "foo@foo.com", "bar")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if us != "foobarbaz" {
+		t.Fatalf("want %q but %q", "foobarbaz", us)
+	}
+	if c.UserSession != "foobarbaz" {
+		t.Fatalf("want %q but %q", "foobarbaz", c.UserSession)
+	}
+	if called != 1 {
+		t.Fatalf("want %d but %d", 1, called)
+	}
+}
+
+func TestLogin_MFAFailed(t *testing.T) {
+	ts := newMFAServer(t)
+	defer ts.Close()
+
+	c := NewClient(WithLoginURL(ts.URL + "/secure/login"))
+	if _, err := c.Login(context.Background(), "foo@foo.com", "bar"); !errors.Is(err, ErrOTPRequired) {
+		t.Fatalf("want %v but %v", ErrOTPRequired, err)
+	}
+
+	c = NewClient(
+		WithLoginURL(ts.URL+"/secure/login"),
+		WithOTPProvider(func(ctx context.Context) (string, error) { return "000000", nil }),
+	)
+	if _, err := c.Login(context.Background(), "foo@foo.com", "bar"); !errors.Is(err, ErrInvalidOTP) {
+		t.Fatalf("want %v but %v", ErrInvalidOTP, err)
+	}
+
+	providerErr := errors.New("canceled by user")
+	c = NewClient(
+		WithLoginURL(ts.URL+"/secure/login"),
+		WithOTPProvider(func(ctx context.Context) (string, error) { return "", providerErr }),
+	)
+	if _, err := c.Login(context.Background(), "foo@foo.com", "bar"); err != providerErr {
+		t.Fatalf("want %v but %v", providerErr, err)
+	}
+}
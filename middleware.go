@@ -0,0 +1,136 @@
+package nico
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Doer sends an HTTP request and returns an HTTP response.
+// *http.Client satisfies this interface.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc is an adapter to allow the use of ordinary functions as Doer.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps next to process the request and the response of every API call.
+// It is called on every attempt of the retries.
+type Middleware func(next Doer) Doer
+
+// Use adds the middlewares to c.
+// The middleware added first is the outermost.
+// Use must not be called concurrently with the API calls.
+func (c *Client) Use(mws ...Middleware) {
+	c.middlewares = append(c.middlewares, mws...)
+}
+
+// WithMiddleware adds the middlewares to the client.
+func WithMiddleware(mws ...Middleware) Option {
+	return func(c *Client) { c.Use(mws...) }
+}
+
+func (c *Client) chain(d Doer) Doer {
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		d = c.middlewares[i](d)
+	}
+	return d
+}
+
+type endpointKey struct{}
+
+// EndpointFromContext returns the endpoint of the API call from the context of the request.
+func EndpointFromContext(ctx context.Context) (Endpoint, bool) {
+	ep, ok := ctx.Value(endpointKey{}).(Endpoint)
+	return ep, ok
+}
+
+// HeaderMiddleware sets the header of key to the value returned by fn.
+// The header is not set if fn returns the empty string.
+// It is useful to attach trace IDs to the requests.
+func HeaderMiddleware(key string, fn func(req *http.Request) string) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if v := fn(req); v != "" {
+				req = req.Clone(req.Context())
+				req.Header.Set(key, v)
+			}
+			return next.Do(req)
+		})
+	}
+}
+
+// SlogMiddleware logs every API call with its latency to logger.
+// The user_session cookie is redacted from the logged headers.
+func SlogMiddleware(logger *slog.Logger) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			ep, _ := EndpointFromContext(ctx)
+			start := time.Now()
+			resp, err := next.Do(req)
+			attrs := []slog.Attr{
+				slog.String("endpoint", string(ep)),
+				slog.String("method", req.Method),
+				slog.String("url", req.URL.Redacted()),
+				slog.Any("request_header", RedactHeader(req.Header)),
+				slog.Duration("latency", time.Since(start)),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+				logger.LogAttrs(ctx, slog.LevelError, "niconico api call failed", attrs...)
+				return nil, err
+			}
+			attrs = append(attrs,
+				slog.Int("status", resp.StatusCode),
+				slog.Any("response_header", RedactHeader(resp.Header)),
+			)
+			logger.LogAttrs(ctx, slog.LevelInfo, "niconico api call", attrs...)
+			return resp, nil
+		})
+	}
+}
+
+// redactedCookies is the cookies whose value is redacted by RedactHeader.
+var redactedCookies = map[string]bool{
+	"user_session":        true,
+	"user_session_secure": true,
+}
+
+// RedactHeader returns a copy of h whose session cookies are redacted.
+func RedactHeader(h http.Header) http.Header {
+	rh := h.Clone()
+	for i, v := range rh["Cookie"] {
+		pairs := strings.Split(v, ";")
+		for k, pair := range pairs {
+			name, _, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && redactedCookies[name] {
+				pairs[k] = " " + name + "=REDACTED"
+				if k == 0 {
+					pairs[k] = pairs[k][1:]
+				}
+			}
+		}
+		rh["Cookie"][i] = strings.Join(pairs, ";")
+	}
+	for i, v := range rh["Set-Cookie"] {
+		name, rest, ok := strings.Cut(v, "=")
+		if !ok || !redactedCookies[strings.TrimSpace(name)] {
+			continue
+		}
+		attrs := ""
+		if j := strings.Index(rest, ";"); j >= 0 {
+			attrs = rest[j:]
+		}
+		rh["Set-Cookie"][i] = name + "=REDACTED" + attrs
+	}
+	return rh
+}
@@ -0,0 +1,128 @@
+package nico
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_Use(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Trace-Id"); got != "trace-1" {
+			t.Errorf("want %q but %q", "trace-1", got)
+		}
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "user_session", Value: "foobarbaz"})
+			http.Redirect(w, r, "/top", http.StatusFound)
+		case "/api/getpostkey":
+			io.WriteString(w, "postkey=foo")
+		default:
+			io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><getplayerstatus status="ok"></getplayerstatus>`)
+		}
+	}))
+	defer ts.Close()
+
+	var order []string
+	var eps []Endpoint
+	mw := func(name string) Middleware {
+		return func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				if name == "outer" {
+					ep, _ := EndpointFromContext(req.Context())
+					eps = append(eps, ep)
+				}
+				return next.Do(req)
+			})
+		}
+	}
+
+	c := NewClient(
+		WithLoginURL(ts.URL+"/login"),
+		WithLiveBaseURL(ts.URL),
+		WithMiddleware(mw("outer")),
+	)
+	c.Use(mw("inner"), HeaderMiddleware("X-Trace-Id", func(*http.Request) string { return "trace-1" }))
+
+	ctx := context.Background()
+	if _, err := c.Login(ctx, "foo@foo.com", "bar"); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if _, err := c.GetPostkey(ctx, 1); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if _, err := c.GetPlayerStatus(ctx, "lv123456789"); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	if got, want := strings.Join(order, ","), "outer,inner,outer,inner,outer,inner"; got != want {
+		t.Fatalf("want %q but %q", want, got)
+	}
+	wantEps := []Endpoint{EndpointLogin, EndpointGetPostkey, EndpointGetPlayerStatus}
+	for i, ep := range wantEps {
+		if eps[i] != ep {
+			t.Fatalf("want %q but %q", ep, eps[i])
+		}
+	}
+}
+
+func TestSlogMiddleware(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "user_session", Value: "newsession", Path: "/"})
+		io.WriteString(w, "postkey=foo")
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	c := &Client{
+		liveBaseRawurl: ts.URL,
+		UserSession:    "oldsession",
+	}
+	c.Use(SlogMiddleware(slog.New(slog.NewJSONHandler(&buf, nil))))
+	if _, err := c.GetPostkey(context.Background(), 1); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	log := buf.String()
+	for _, s := range []string{`"endpoint":"getpostkey"`, `"status":200`, `"latency":`, "user_session=REDACTED"} {
+		if !strings.Contains(log, s) {
+			t.Fatalf("%q should contain %q", log, s)
+		}
+	}
+	for _, s := range []string{"oldsession", "newsession"} {
+		if strings.Contains(log, s) {
+			t.Fatalf("%q should not contain %q", log, s)
+		}
+	}
+}
+
+func TestRedactHeader(t *testing.T) {
+	h := http.Header{}
+	h.Add("Cookie", "nicosid=123; user_session=foo; nicohistory=bar")
+	h.Add("Cookie", "user_session=foo")
+	h.Add("Set-Cookie", "user_session=foo; Path=/; HttpOnly")
+	h.Add("Set-Cookie", "nicosid=123; Path=/")
+
+	rh := RedactHeader(h)
+	if got, want := rh["Cookie"][0], "nicosid=123; user_session=REDACTED; nicohistory=bar"; got != want {
+		t.Fatalf("want %q but %q", want, got)
+	}
+	if got, want := rh["Cookie"][1], "user_session=REDACTED"; got != want {
+		t.Fatalf("want %q but %q", want, got)
+	}
+	if got, want := rh["Set-Cookie"][0], "user_session=REDACTED; Path=/; HttpOnly"; got != want {
+		t.Fatalf("want %q but %q", want, got)
+	}
+	if got, want := rh["Set-Cookie"][1], "nicosid=123; Path=/"; got != want {
+		t.Fatalf("want %q but %q", want, got)
+	}
+	if got := h.Get("Cookie"); !strings.Contains(got, "user_session=foo") {
+		t.Fatalf("original header should not be modified: %q", got)
+	}
+}
@@ -2,11 +2,11 @@
 package nico
 
 import (
-	"bufio"
 	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -14,6 +14,7 @@ import (
 	"path"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -27,58 +28,279 @@ var findCommunityID = func() func(string) string {
 }()
 
 // Client is a API client for niconico.
+// Cookies received from niconico are kept in Jar.
+//
+// Client is safe for concurrent use by multiple goroutines
+// once it is configured.
 type Client struct {
 	http.Client
+	mu                  sync.RWMutex
 	loginRawurl         string
+	logoutRawurl        string
 	liveBaseRawurl      string
 	communityBaseRawurl string
 	ceBaseRawurl        string
+	nvapiBaseRawurl     string
+	userAgent           string
+	header              http.Header
+	retryPolicy         RetryPolicy
+	limiters            map[string]*limiter
+	middlewares         []Middleware
+	otpProvider         OTPProvider
+	trustedDeviceName   string
+	sessionStore        SessionStore
+	credentials         CredentialsProvider
+	reloginMu           sync.Mutex
+	sessionLoaded       bool
+	dialContext         DialContextFunc
 	UserSession         string
 }
 
-// NewClient return new niconico client.
-func NewClient() *Client {
-	return &Client{
+// NewClient return new niconico client configured by opts.
+func NewClient(opts ...Option) *Client {
+	c := &Client{
 		loginRawurl:         "https://secure.nicovideo.jp/secure/login",
+		logoutRawurl:        "https://secure.nicovideo.jp/secure/logout",
 		liveBaseRawurl:      "http://live.nicovideo.jp",
 		communityBaseRawurl: "http://com.nicovideo.jp",
 		ceBaseRawurl:        "http://api.ce.nicovideo.jp",
+		nvapiBaseRawurl:     "https://nvapi.nicovideo.jp",
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.Jar == nil {
+		c.Jar = NewJar()
+	}
+	return c
+}
+
+func (c *Client) newRequest(ctx context.Context, method, rawurl string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, rawurl, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, vs := range c.header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return req, nil
+}
+
+// authKey is the context key of the user session of the request of newAuthRequest.
+type authKey struct{}
+
+// newAuthRequest returns the request of the endpoint that requires login.
+// The session is loaded from the SessionStore first if UserSession is not set.
+// The cookies in the jar are added by the domain when the request is sent.
+// UserSession is added if the jar does not have the same one,
+// and it is sent ahead of the one in the jar.
+func (c *Client) newAuthRequest(ctx context.Context, method, rawurl string, body io.Reader) (*http.Request, error) {
+	if err := c.loadSession(ctx); err != nil {
+		return nil, err
+	}
+	req, err := c.newRequest(ctx, method, rawurl, body)
+	if err != nil {
+		return nil, err
+	}
+	us := c.userSession()
+	c.addUserSession(req, us)
+	return req.WithContext(context.WithValue(ctx, authKey{}, us)), nil
+}
+
+// addUserSession makes req send us as user_session.
+// The session in *Jar is replaced since the server reads only one of the duplicated cookies.
+func (c *Client) addUserSession(req *http.Request, us string) {
+	if us == "" {
+		return
+	}
+	v, ok := cookieValue(c.Jar, req.URL, "user_session")
+	if v == us {
+		return
+	}
+	if j, isJar := c.Jar.(*Jar); ok && isJar && j.replace(req.URL.Hostname(), "user_session", us) {
+		return
+	}
+	req.AddCookie(&http.Cookie{Name: "user_session", Value: us})
+}
+
+// do sends req to ep. If req is of newAuthRequest and the response requires login,
+// it logins again by the CredentialsProvider and sends req once more.
+func (c *Client) do(req *http.Request, ep Endpoint) (*http.Response, error) {
+	resp, err := c.send(req, ep)
+	stale, auth := req.Context().Value(authKey{}).(string)
+	if err != nil || !auth || c.credentials == nil || ep == EndpointLogout || !c.isLoginRequired(resp) {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	if err := c.relogin(req.Context(), stale); err != nil {
+		return nil, err
+	}
+	req, err = c.renewAuthRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(req, ep)
+}
+
+// renewAuthRequest returns the copy of req with the current user session.
+func (c *Client) renewAuthRequest(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, errors.New("cannot send the request body again")
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	// The cookies of the jar are added again when r is sent.
+	r.Header.Del("Cookie")
+	c.addUserSession(r, c.userSession())
+	return r, nil
+}
+
+// isLoginRequired reports whether resp is unauthorized or redirected to the login page.
+func (c *Client) isLoginRequired(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	login, err := url.Parse(c.loginRawurl)
+	if err != nil {
+		return false
+	}
+	u := resp.Request.URL
+	if loc, err := resp.Location(); err == nil {
+		u = loc
+	}
+	return u.Host == login.Host && strings.HasPrefix(u.Path, login.Path)
+}
+
+// send sends req to ep through the middlewares with the rate limit
+// and retries it according to the retry policy.
+func (c *Client) send(req *http.Request, ep Endpoint) (*http.Response, error) {
+	ctx := req.Context()
+	req = req.WithContext(context.WithValue(ctx, endpointKey{}, ep))
+	doer := c.chain(c.httpClient())
+	p := c.retryPolicy
+	retry := p.enabled(req, ep)
+	for attempt := 1; ; attempt++ {
+		if err := c.waitRateLimit(ctx, req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+		resp, err := doer.Do(req)
+		if !retry || attempt >= p.MaxAttempts || !shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+
+		d := p.backoff(attempt)
+		if ra, ok := retryAfter(resp, time.Now()); ok {
+			d = ra
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if err := sleep(ctx, d); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type noRedirectKey struct{}
+
+// withoutRedirect returns req that does not follow redirects.
+func withoutRedirect(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), noRedirectKey{}, true))
+}
+
+// httpClient returns a copy of the underlying http.Client
+// whose redirect behavior is decided by each request.
+func (c *Client) httpClient() *http.Client {
+	hc := c.Client
+	cr := c.CheckRedirect
+	hc.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if noRedirect, _ := req.Context().Value(noRedirectKey{}).(bool); noRedirect {
+			return http.ErrUseLastResponse
+		}
+		if cr != nil {
+			return cr(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &hc
+}
+
+func (c *Client) userSession() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.UserSession
+}
+
+func (c *Client) setUserSession(us string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.UserSession = us
 }
 
 // Login is login to niconico and get user session.
+// If the account has two-step verification,
+// the one-time password is asked to the OTPProvider of the client.
+// The session is saved to the SessionStore of the client.
 func (c *Client) Login(ctx context.Context, mail, password string) (string, error) {
 	v := url.Values{}
 	v.Set("mail", mail)
 	v.Set("password", password)
 
-	req, err := http.NewRequest(http.MethodPost, c.loginRawurl, strings.NewReader(v.Encode()))
+	req, err := c.newRequest(ctx, http.MethodPost, c.loginRawurl, strings.NewReader(v.Encode()))
 	if err != nil {
 		return "", err
 	}
-	req = req.WithContext(ctx)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	cr := c.CheckRedirect
-	defer func() { c.CheckRedirect = cr }()
-	c.CheckRedirect = func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }
-	resp, err := c.Do(req)
+	resp, err := c.do(withoutRedirect(req), EndpointLogin)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusFound {
-		return "", errors.New(resp.Status)
+		return "", newAPIError(EndpointLogin, resp)
+	}
+
+	us := findUserSession(resp.Cookies())
+	if us == "" {
+		loc, err := resp.Location()
+		if err != nil || !isMFARedirect(loc) {
+			return "", ErrLoginFailed
+		}
+		if us, err = c.loginMFA(ctx, loc); err != nil {
+			return "", err
+		}
+	}
+	c.setUserSession(us)
+	if err := c.saveSession(ctx, us); err != nil {
+		return "", err
 	}
+	return us, nil
+}
 
-	cookies := resp.Cookies()
+func findUserSession(cookies []*http.Cookie) string {
 	for i := len(cookies) - 1; i >= 0; i-- {
 		if cookies[i].Name == "user_session" {
-			c.UserSession = cookies[i].Value
-			return c.UserSession, nil
+			return cookies[i].Value
 		}
 	}
-	return "", errors.New("login failed")
+	return ""
 }
 
 // GetPostkey gets the key to be specified when posting a comment.
@@ -93,20 +315,18 @@ func (c *Client) GetPostkey(ctx context.Context, thread int64) (string, error) {
 	v.Set("thread", fmt.Sprint(thread))
 	u.RawQuery = v.Encode()
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := c.newAuthRequest(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return "", err
 	}
-	req = req.WithContext(ctx)
-	req.AddCookie(&http.Cookie{Name: "user_session", Value: c.UserSession})
 
-	resp, err := c.Do(req)
+	resp, err := c.do(req, EndpointGetPostkey)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.New(resp.Status)
+		return "", newAPIError(EndpointGetPostkey, resp)
 	}
 
 	b, err := ioutil.ReadAll(resp.Body)
@@ -119,7 +339,7 @@ func (c *Client) GetPostkey(ctx context.Context, thread int64) (string, error) {
 	}
 	postkey := rv.Get("postkey")
 	if postkey == "" {
-		return "", errors.New("postkey is empty")
+		return "", ErrPostkeyEmpty
 	}
 	return postkey, nil
 }
@@ -135,27 +355,22 @@ func (c *Client) FollowCommunity(ctx context.Context, communityID string) error
 	v := url.Values{}
 	v.Set("mode", "commit")
 
-	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(v.Encode()))
+	req, err := c.newAuthRequest(ctx, http.MethodPost, u.String(), strings.NewReader(v.Encode()))
 	if err != nil {
 		return err
 	}
-	req = req.WithContext(ctx)
 	req.Header.Set("Referer", u.String())
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.AddCookie(&http.Cookie{Name: "user_session", Value: c.UserSession})
 
-	cr := c.CheckRedirect
-	defer func() { c.CheckRedirect = cr }()
-	c.CheckRedirect = func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }
-	resp, err := c.Do(req)
+	resp, err := c.do(withoutRedirect(req), EndpointFollowCommunity)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusFound {
-		return errors.New(resp.Status)
+		return newAPIError(EndpointFollowCommunity, resp)
 	} else if !strings.Contains(resp.Header.Get("Location"), "done") {
-		return errors.New("community follow failed")
+		return ErrCommunityFollowFailed
 	}
 	return nil
 }
@@ -167,20 +382,18 @@ func (c *Client) getLeaveCommunityFormData(ctx context.Context, communityID stri
 	}
 	u.Path = path.Join("leave", communityID)
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := c.newAuthRequest(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
-	req.AddCookie(&http.Cookie{Name: "user_session", Value: c.UserSession})
 
-	resp, err := c.Do(req)
+	resp, err := c.do(req, EndpointLeaveCommunityForm)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(resp.Status)
+		return nil, newAPIError(EndpointLeaveCommunityForm, resp)
 	}
 
 	doc, err := goquery.NewDocumentFromResponse(resp)
@@ -216,27 +429,22 @@ func (c *Client) LeaveCommunity(ctx context.Context, communityID string) error {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(v.Encode()))
+	req, err := c.newAuthRequest(ctx, http.MethodPost, u.String(), strings.NewReader(v.Encode()))
 	if err != nil {
 		return err
 	}
-	req = req.WithContext(ctx)
 	req.Header.Set("Referer", u.String())
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.AddCookie(&http.Cookie{Name: "user_session", Value: c.UserSession})
 
-	cr := c.CheckRedirect
-	defer func() { c.CheckRedirect = cr }()
-	c.CheckRedirect = func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }
-	resp, err := c.Do(req)
+	resp, err := c.do(withoutRedirect(req), EndpointLeaveCommunity)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusFound {
-		return errors.New(resp.Status)
+		return newAPIError(EndpointLeaveCommunity, resp)
 	} else if !strings.Contains(resp.Header.Get("Location"), "done") {
-		return errors.New("community leave failed")
+		return ErrCommunityLeaveFailed
 	}
 	return nil
 }
@@ -250,19 +458,18 @@ func (c *Client) GetCommunityIDFromLiveID(ctx context.Context, liveID string) (s
 	}
 	u.Path = path.Join("watch", liveID)
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := c.newRequest(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return "", err
 	}
-	req = req.WithContext(ctx)
 
-	resp, err := c.Do(req)
+	resp, err := c.do(req, EndpointWatch)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.New(resp.Status)
+		return "", newAPIError(EndpointWatch, resp)
 	}
 
 	doc, err := goquery.NewDocumentFromResponse(resp)
@@ -271,7 +478,7 @@ func (c *Client) GetCommunityIDFromLiveID(ctx context.Context, liveID string) (s
 	}
 	href, ok := doc.Find(".shosai > a").Attr("href")
 	if !ok {
-		return "", errors.New("community not found")
+		return "", ErrCommunityNotFound
 	}
 	return findCommunityID(href), nil
 }
@@ -283,8 +490,7 @@ func (c *Client) MakeLiveClient(ctx context.Context, liveID string) (*LiveClient
 		return nil, err
 	}
 
-	var d net.Dialer
-	conn, err := d.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", ps.Ms.Addr, ps.Ms.Port))
+	conn, err := c.dial(ctx, ps.Ms.Addr, ps.Ms.Port)
 	if err != nil {
 		return nil, err
 	}
@@ -295,52 +501,54 @@ func (c *Client) MakeLiveClient(ctx context.Context, liveID string) (*LiveClient
 	return &LiveClient{Client: c, PlayerStatus: ps, conn: conn}, nil
 }
 
+// dial connects to the comment server of addr and port.
+func (c *Client) dial(ctx context.Context, addr string, port int64) (net.Conn, error) {
+	dial := c.dialContext
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	return dial(ctx, "tcp", net.JoinHostPort(addr, fmt.Sprint(port)))
+}
+
 // LiveClient is a client with broadcast information.
 type LiveClient struct {
 	*Client
 	PlayerStatus *PlayerStatus
+	connMu       sync.Mutex
 	conn         net.Conn
 }
 
+func (c *LiveClient) getConn() net.Conn {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	return c.conn
+}
+
+func (c *LiveClient) setConn(conn net.Conn) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	c.conn = conn
+}
+
 // StreamingComment return the channel that receives comment.
-func (c *LiveClient) StreamingComment(ctx context.Context, resFrom int64) (chan Comment, error) {
-	b, err := xml.Marshal(SendThread{Thread: c.PlayerStatus.Ms.Thread, Version: 20061206, ResFrom: resFrom})
-	if err != nil {
-		return nil, err
+// The channel is closed after CommentError is sent when reading fails or ctx is done.
+func (c *LiveClient) StreamingComment(ctx context.Context, resFrom int64, opts ...StreamOption) (chan Comment, error) {
+	var cfg streamConfig
+	for _, opt := range opts {
+		opt(&cfg)
 	}
-	b = append(b, 0)
-	_, err = c.conn.Write(b)
-	if err != nil {
+
+	conn := c.getConn()
+	if err := sendThread(conn, c.PlayerStatus.Ms.Thread, resFrom); err != nil {
 		return nil, err
 	}
 
-	r := bufio.NewReader(c.conn)
-	ch := make(chan Comment)
+	ch := make(chan Comment, 1)
 	go func() {
 		defer close(ch)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
-			rb, err := r.ReadSlice(0)
-			if err != nil {
-				ch <- &CommentError{err}
-			}
-			var thread Thread
-			if err := xml.Unmarshal(rb, &thread); err == nil {
-				ch <- &thread
-			}
-			var chat Chat
-			if err := xml.Unmarshal(rb, &chat); err == nil {
-				ch <- &chat
-			}
-			var chatResult ChatResult
-			if err := xml.Unmarshal(rb, &chatResult); err == nil {
-				ch <- &chatResult
-			}
-		}
+		s := commentStream{lc: c, cfg: cfg, ch: ch, seen: newChatSet(10000), resFrom: resFrom}
+		s.run(ctx, conn)
 	}()
 	return ch, nil
 }
@@ -352,7 +560,7 @@ func (c *LiveClient) PostComment(ctx context.Context, comment string, mail Mail)
 		return err
 	}
 	chat := SendChat{
-		Vpos:    (time.Now().UnixNano() - c.PlayerStatus.Stream.BaseTime*int64(time.Second)) / (int64(time.Millisecond) * 10),
+		Vpos:    c.PlayerStatus.vpos(time.Now()),
 		Mail:    mail.String(),
 		UserID:  fmt.Sprint(c.PlayerStatus.User.UserID),
 		Postkey: postkey,
@@ -363,7 +571,7 @@ func (c *LiveClient) PostComment(ctx context.Context, comment string, mail Mail)
 		return err
 	}
 	b = append(b, 0)
-	if _, err := c.conn.Write(b); err != nil {
+	if _, err := c.getConn().Write(b); err != nil {
 		return err
 	}
 	return nil
@@ -391,6 +599,9 @@ type Thread struct {
 	Ticket     string   `xml:"ticket,attr"`
 	Revision   int64    `xml:"revision,attr"`
 	ServerTime int64    `xml:"server_time,attr"`
+
+	// Room is the label of the room set by StreamingRoomsComment.
+	Room string `xml:"-"`
 }
 
 func (t *Thread) comment() {}
@@ -411,6 +622,9 @@ type Chat struct {
 	Locale    string   `xml:"locale,attr"`
 	Score     int64    `xml:"score,attr"`
 	Comment   string   `xml:",chardata"`
+
+	// Room is the label of the room set by StreamingRoomsComment.
+	Room string `xml:"-"`
 }
 
 func (c *Chat) comment() {}
@@ -425,11 +639,36 @@ type ChatResult struct {
 
 func (r *ChatResult) comment() {}
 
+// Statuses of ChatResult.
+const (
+	ChatResultSuccess        = 0
+	ChatResultFailure        = 1 // Rejected such as by posting too often.
+	ChatResultInvalidThread  = 2
+	ChatResultInvalidTicket  = 3
+	ChatResultInvalidPostkey = 4
+	ChatResultLocked         = 5
+	ChatResultReadOnly       = 6
+	ChatResultTooLong        = 8
+)
+
+// Err returns ChatResultError if the comment is rejected, otherwise nil.
+func (r *ChatResult) Err() error {
+	if r.Status == ChatResultSuccess {
+		return nil
+	}
+	return &ChatResultError{Thread: r.Thread, Status: r.Status}
+}
+
 // CommentError is a struct containing error of StreamingComment function.
 type CommentError struct{ error }
 
 func (e *CommentError) comment() {}
 
+// Unwrap returns the cause of the error.
+func (e *CommentError) Unwrap() error {
+	return e.error
+}
+
 // SendChat is a struct to use when posting comment.
 type SendChat struct {
 	XMLName xml.Name `xml:"chat"`
@@ -445,8 +684,52 @@ const (
 	PlayerStatusErrorCodeFull                   = "full"
 	PlayerStatusErrorCodeNotlogin               = "notlogin"
 	PlayerStatusErrorCodeRequireCommunityMember = "require_community_member"
+	PlayerStatusErrorCodeClosed                 = "closed"
+	PlayerStatusErrorCodeComingsoon             = "comingsoon"
+	PlayerStatusErrorCodeNotfound               = "notfound"
+	PlayerStatusErrorCodeDeletedbyuser          = "deletedbyuser"
+	PlayerStatusErrorCodeDeletedbyvisor         = "deletedbyvisor"
+	PlayerStatusErrorCodeNoauth                 = "noauth"
+	PlayerStatusErrorCodeTimeshiftTicketExhaust = "timeshift_ticket_exhaust"
+	PlayerStatusErrorCodeUsertimeshift          = "usertimeshift"
+	PlayerStatusErrorCodeMaintenance            = "maintenance"
+	PlayerStatusErrorCodeUnknown                = "unknown"
+)
+
+// PlayerStatusErrorKind is a classification of the error code of PlayerStatus.
+type PlayerStatusErrorKind int
+
+// Kinds of the error code of PlayerStatus.
+const (
+	// PlayerStatusErrorFatal is the error that does not change by retrying.
+	PlayerStatusErrorFatal PlayerStatusErrorKind = iota
+
+	// PlayerStatusErrorRetryable is the error that may succeed by retrying later.
+	PlayerStatusErrorRetryable
+
+	// PlayerStatusErrorAuth is the error that requires login or permission.
+	PlayerStatusErrorAuth
 )
 
+var playerStatusErrorCodes = map[string]struct {
+	kind   PlayerStatusErrorKind
+	target error
+}{
+	PlayerStatusErrorCodeFull:                   {PlayerStatusErrorRetryable, ErrSeatsFull},
+	PlayerStatusErrorCodeComingsoon:             {PlayerStatusErrorRetryable, ErrLiveNotStarted},
+	PlayerStatusErrorCodeMaintenance:            {PlayerStatusErrorRetryable, ErrMaintenance},
+	PlayerStatusErrorCodeUnknown:                {PlayerStatusErrorRetryable, nil},
+	PlayerStatusErrorCodeNotlogin:               {PlayerStatusErrorAuth, ErrNotLoggedIn},
+	PlayerStatusErrorCodeNoauth:                 {PlayerStatusErrorAuth, ErrNoPermission},
+	PlayerStatusErrorCodeRequireCommunityMember: {PlayerStatusErrorAuth, ErrRequireCommunityMember},
+	PlayerStatusErrorCodeClosed:                 {PlayerStatusErrorFatal, ErrLiveClosed},
+	PlayerStatusErrorCodeNotfound:               {PlayerStatusErrorFatal, ErrLiveNotFound},
+	PlayerStatusErrorCodeDeletedbyuser:          {PlayerStatusErrorFatal, ErrLiveDeleted},
+	PlayerStatusErrorCodeDeletedbyvisor:         {PlayerStatusErrorFatal, ErrLiveDeleted},
+	PlayerStatusErrorCodeTimeshiftTicketExhaust: {PlayerStatusErrorFatal, ErrTimeshiftUnavailable},
+	PlayerStatusErrorCodeUsertimeshift:          {PlayerStatusErrorFatal, ErrTimeshiftUnavailable},
+}
+
 // PlayerStatusError is an error to return if Status of PlayerStatus is not ok.
 type PlayerStatusError struct {
 	Status string
@@ -456,3 +739,20 @@ type PlayerStatusError struct {
 func (e PlayerStatusError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Status, e.Code)
 }
+
+// Kind returns the classification of Code.
+// Unknown codes are regarded as fatal.
+func (e PlayerStatusError) Kind() PlayerStatusErrorKind {
+	return playerStatusErrorCodes[e.Code].kind
+}
+
+// Temporary reports whether the request may succeed by retrying later.
+func (e PlayerStatusError) Temporary() bool {
+	return e.Kind() == PlayerStatusErrorRetryable
+}
+
+// Is reports whether the error corresponds to target.
+func (e PlayerStatusError) Is(target error) bool {
+	t := playerStatusErrorCodes[e.Code].target
+	return t != nil && t == target
+}
@@ -15,6 +15,9 @@ func TestNewClient(t *testing.T) {
 	if got, want := c.loginRawurl, "https://secure.nicovideo.jp/secure/login"; got != want {
 		t.Fatalf("loginRawurl: %v, want %v", got, want)
 	}
+	if got, want := c.logoutRawurl, "https://secure.nicovideo.jp/secure/logout"; got != want {
+		t.Fatalf("logoutRawurl: %v, want %v", got, want)
+	}
 	if got, want := c.liveBaseRawurl, "http://live.nicovideo.jp"; got != want {
 		t.Fatalf("liveBaseRawurl: %v, want %v", got, want)
 	}
@@ -24,6 +27,9 @@ func TestNewClient(t *testing.T) {
 	if got, want := c.ceBaseRawurl, "http://api.ce.nicovideo.jp"; got != want {
 		t.Fatalf("ceBaseRawurl: %v, want %v", got, want)
 	}
+	if got, want := c.nvapiBaseRawurl, "https://nvapi.nicovideo.jp"; got != want {
+		t.Fatalf("nvapiBaseRawurl: %v, want %v", got, want)
+	}
 }
 
 func TestLogin(t *testing.T) {
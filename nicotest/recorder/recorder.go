@@ -0,0 +1,252 @@
+// Package recorder provides an http.RoundTripper that records
+// niconico API calls to a fixture file and replays them offline.
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/178inaba/nico"
+)
+
+// Mode is a mode of Recorder.
+type Mode int
+
+// Modes of Recorder.
+const (
+	// ModeReplay replays the interactions in the fixture file.
+	ModeReplay Mode = iota
+
+	// ModeRecord sends the requests to the real transport
+	// and records the interactions to the fixture file.
+	ModeRecord
+)
+
+// Redacted is the value replaced with the credentials.
+const Redacted = "REDACTED"
+
+// Interaction is a pair of the recorded request and response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Option is a function that configures a Recorder.
+type Option func(*Recorder)
+
+// WithTransport sets the transport used in ModeRecord.
+// http.DefaultTransport is used by default.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(r *Recorder) { r.transport = rt }
+}
+
+// WithScrubber adds the function that removes the credentials
+// from the interaction before it is saved.
+func WithScrubber(fn func(*Interaction)) Option {
+	return func(r *Recorder) { r.scrubbers = append(r.scrubbers, fn) }
+}
+
+// Recorder is an http.RoundTripper that records or replays the interactions.
+type Recorder struct {
+	mu           sync.Mutex
+	name         string
+	mode         Mode
+	transport    http.RoundTripper
+	scrubbers    []func(*Interaction)
+	interactions []*Interaction
+	used         []bool
+}
+
+// New returns a Recorder of the fixture file of name.
+// In ModeReplay the fixture file is loaded.
+func New(name string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		name:      name,
+		mode:      mode,
+		transport: http.DefaultTransport,
+		scrubbers: []func(*Interaction){scrubCredentials},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if mode == ModeReplay {
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &r.interactions); err != nil {
+			return nil, fmt.Errorf("recorder: %s: %v", name, err)
+		}
+		r.used = make([]bool, len(r.interactions))
+	}
+	return r, nil
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModeRecord {
+		return r.record(req)
+	}
+	return r.replay(req)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	i := &Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: req.Header.Clone(),
+			Body:   string(reqBody),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       string(respBody),
+		},
+	}
+	for _, scrub := range r.scrubbers {
+		scrub(i)
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, i)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := -1
+	for k, i := range r.interactions {
+		if !match(i, req) {
+			continue
+		}
+		last = k
+		if !r.used[k] {
+			break
+		}
+	}
+	if last < 0 {
+		return nil, fmt.Errorf("recorder: no interaction for %s %s", req.Method, req.URL)
+	}
+	r.used[last] = true
+
+	i := r.interactions[last]
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Response.StatusCode, http.StatusText(i.Response.StatusCode)),
+		StatusCode:    i.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        i.Response.Header.Clone(),
+		Body:          ioutil.NopCloser(strings.NewReader(i.Response.Body)),
+		ContentLength: int64(len(i.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Stop saves the recorded interactions to the fixture file in ModeRecord.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.name, append(b, '\n'), 0644)
+}
+
+// Interactions returns the recorded or loaded interactions.
+func (r *Recorder) Interactions() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Interaction(nil), r.interactions...)
+}
+
+// match reports whether i is the interaction of req.
+// The order of the query parameters is ignored.
+func match(i *Interaction, req *http.Request) bool {
+	if i.Request.Method != req.Method {
+		return false
+	}
+	u, err := url.Parse(i.Request.URL)
+	if err != nil {
+		return false
+	}
+	return u.Host == req.URL.Host && u.Path == req.URL.Path &&
+		u.Query().Encode() == req.URL.Query().Encode()
+}
+
+// credentialFields is the form fields that are redacted.
+var credentialFields = []string{"mail", "mail_tel", "password", "otp"}
+
+func scrubCredentials(i *Interaction) {
+	i.Request.Header = nico.RedactHeader(i.Request.Header)
+	i.Request.Header.Del("Authorization")
+	i.Response.Header = nico.RedactHeader(i.Response.Header)
+
+	if v, err := url.ParseQuery(i.Request.Body); err == nil && i.Request.Body != "" {
+		scrubbed := false
+		for _, f := range credentialFields {
+			if _, ok := v[f]; ok {
+				v.Set(f, Redacted)
+				scrubbed = true
+			}
+		}
+		if scrubbed {
+			i.Request.Body = v.Encode()
+		}
+	}
+}
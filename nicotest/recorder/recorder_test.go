@@ -0,0 +1,112 @@
+package recorder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/178inaba/nico"
+)
+
+func TestRecorder_Replay(t *testing.T) {
+	r, err := New("testdata/nico.json", ModeReplay)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	c := nico.NewClient(nico.WithTransport(r))
+	c.UserSession = "user-session"
+	ctx := context.Background()
+
+	ps, err := c.GetPlayerStatus(ctx, "lv123456789")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if ps.Stream.Title != "test-title" {
+		t.Fatalf("want %q but %q", "test-title", ps.Stream.Title)
+	}
+	postkey, err := c.GetPostkey(ctx, ps.Ms.Thread)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if postkey != "foobarbaz" {
+		t.Fatalf("want %q but %q", "foobarbaz", postkey)
+	}
+	ui, err := c.GetUserInfo(ctx, 2525)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if ui.Nickname != "foo" {
+		t.Fatalf("want %q but %q", "foo", ui.Nickname)
+	}
+
+	if _, err := c.GetUserInfo(ctx, 1); err == nil {
+		t.Fatalf("should be fail: %v", err)
+	}
+}
+
+func TestRecorder_Record(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "user_session", Value: "secret-session"})
+			http.Redirect(w, r, "/top", http.StatusFound)
+		case "/api/getpostkey":
+			io.WriteString(w, "postkey="+r.URL.Query().Get("thread"))
+		}
+	}))
+	defer ts.Close()
+
+	name := filepath.Join(t.TempDir(), "fixture.json")
+	r, err := New(name, ModeRecord)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	c := nico.NewClient(nico.WithTransport(r), nico.WithLoginURL(ts.URL+"/login"), nico.WithLiveBaseURL(ts.URL))
+	ctx := context.Background()
+	if _, err := c.Login(ctx, "foo@foo.com", "secret-password"); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	for _, thread := range []int64{1, 2} {
+		if _, err := c.GetPostkey(ctx, thread); err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+	}
+	if err := r.Stop(); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	for _, s := range []string{"secret-session", "secret-password", "foo%40foo.com"} {
+		if strings.Contains(string(b), s) {
+			t.Fatalf("fixture should not contain %q", s)
+		}
+	}
+	if n := len(r.Interactions()); n != 3 {
+		t.Fatalf("want %d but %d", 3, n)
+	}
+
+	ts.Close()
+	rr, err := New(name, ModeReplay)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	c = nico.NewClient(nico.WithTransport(rr), nico.WithLiveBaseURL(ts.URL))
+	for _, thread := range []int64{2, 1} {
+		postkey, err := c.GetPostkey(ctx, thread)
+		if err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+		if want := fmt.Sprint(thread); postkey != want {
+			t.Fatalf("want %q but %q", want, postkey)
+		}
+	}
+}
@@ -3,7 +3,6 @@ package nico
 import (
 	"context"
 	"encoding/xml"
-	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -38,10 +37,20 @@ type UserInfoError struct {
 	Description string `xml:"description"`
 }
 
+// Error code of UserInfoError.
+const (
+	UserInfoErrorCodeNotFound = "NOT_FOUND"
+)
+
 func (e UserInfoError) Error() string {
 	return fmt.Sprintf("%s: %s: %s", e.Status, e.Code, e.Description)
 }
 
+// Is reports whether the error corresponds to target.
+func (e UserInfoError) Is(target error) bool {
+	return e.Code == UserInfoErrorCodeNotFound && target == ErrUserNotFound
+}
+
 // GetNicovideoUserResponse gets the response of the user information API.
 // No login required.
 func (c *Client) GetNicovideoUserResponse(ctx context.Context, userID int64) (*NicovideoUserResponse, error) {
@@ -55,19 +64,18 @@ func (c *Client) GetNicovideoUserResponse(ctx context.Context, userID int64) (*N
 	v.Set("user_id", strconv.FormatInt(userID, 10))
 	u.RawQuery = v.Encode()
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := c.newRequest(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
 
-	resp, err := c.Do(req)
+	resp, err := c.do(req, EndpointUserInfo)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(resp.Status)
+		return nil, newAPIError(EndpointUserInfo, resp)
 	}
 
 	nur := NicovideoUserResponse{}
@@ -0,0 +1,86 @@
+package nico
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// Option is a function that configures a Client.
+type Option func(*Client)
+
+// WithLoginURL sets the URL of the login endpoint.
+func WithLoginURL(rawurl string) Option {
+	return func(c *Client) { c.loginRawurl = rawurl }
+}
+
+// WithLogoutURL sets the URL of the logout endpoint.
+func WithLogoutURL(rawurl string) Option {
+	return func(c *Client) { c.logoutRawurl = rawurl }
+}
+
+// WithLiveBaseURL sets the base URL of niconico live.
+func WithLiveBaseURL(rawurl string) Option {
+	return func(c *Client) { c.liveBaseRawurl = rawurl }
+}
+
+// WithCommunityBaseURL sets the base URL of niconico community.
+func WithCommunityBaseURL(rawurl string) Option {
+	return func(c *Client) { c.communityBaseRawurl = rawurl }
+}
+
+// WithCEBaseURL sets the base URL of the api.ce.nicovideo.jp API.
+func WithCEBaseURL(rawurl string) Option {
+	return func(c *Client) { c.ceBaseRawurl = rawurl }
+}
+
+// WithNvapiBaseURL sets the base URL of the nvapi.nicovideo.jp API.
+func WithNvapiBaseURL(rawurl string) Option {
+	return func(c *Client) { c.nvapiBaseRawurl = rawurl }
+}
+
+// WithHTTPClient sets the settings of hc to the underlying http.Client.
+// It overwrites the settings of options applied before it.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.Client = *hc }
+}
+
+// WithTransport sets the transport of the underlying http.Client.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) { c.Transport = rt }
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// WithHeader adds the header sent with every request.
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		if c.header == nil {
+			c.header = http.Header{}
+		}
+		c.header.Add(key, value)
+	}
+}
+
+// WithOTPProvider sets the provider of the one-time password
+// used by Login for the account with two-step verification.
+func WithOTPProvider(p OTPProvider) Option {
+	return func(c *Client) { c.otpProvider = p }
+}
+
+// WithTrustedDevice makes Login trust the device of name on two-step verification
+// so that the one-time password is not asked again on the device.
+func WithTrustedDevice(name string) Option {
+	return func(c *Client) { c.trustedDeviceName = name }
+}
+
+// DialContextFunc is a function to connect to the comment server.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// WithDialContext sets the function to connect to the comment server.
+func WithDialContext(fn DialContextFunc) Option {
+	return func(c *Client) { c.dialContext = fn }
+}
@@ -0,0 +1,65 @@
+package nico
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewClientWithOptions(t *testing.T) {
+	rt := &http.Transport{}
+	c := NewClient(
+		WithHTTPClient(&http.Client{Timeout: time.Second}),
+		WithLoginURL("http://login.example.com"),
+		WithLiveBaseURL("http://live.example.com"),
+		WithCommunityBaseURL("http://com.example.com"),
+		WithCEBaseURL("http://ce.example.com"),
+		WithTransport(rt),
+		WithUserAgent("nico-test"),
+		WithHeader("X-Foo", "foo"),
+	)
+	if got, want := c.loginRawurl, "http://login.example.com"; got != want {
+		t.Fatalf("loginRawurl: %v, want %v", got, want)
+	}
+	if got, want := c.liveBaseRawurl, "http://live.example.com"; got != want {
+		t.Fatalf("liveBaseRawurl: %v, want %v", got, want)
+	}
+	if got, want := c.communityBaseRawurl, "http://com.example.com"; got != want {
+		t.Fatalf("communityBaseRawurl: %v, want %v", got, want)
+	}
+	if got, want := c.ceBaseRawurl, "http://ce.example.com"; got != want {
+		t.Fatalf("ceBaseRawurl: %v, want %v", got, want)
+	}
+	if c.Timeout != time.Second {
+		t.Fatalf("want %v but %v", time.Second, c.Timeout)
+	}
+	if c.Transport != rt {
+		t.Fatalf("want %v but %v", rt, c.Transport)
+	}
+}
+
+func TestWithUserAgentAndHeader(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("User-Agent"); got != "nico-test" {
+			t.Errorf("want %q but %q", "nico-test", got)
+		}
+		if got := r.Header["X-Foo"]; len(got) != 2 || got[0] != "foo" || got[1] != "bar" {
+			t.Errorf("want %q but %q", []string{"foo", "bar"}, got)
+		}
+		io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><getplayerstatus status="ok"></getplayerstatus>`)
+	}))
+	defer ts.Close()
+
+	c := NewClient(
+		WithLiveBaseURL(ts.URL),
+		WithUserAgent("nico-test"),
+		WithHeader("X-Foo", "foo"),
+		WithHeader("X-Foo", "bar"),
+	)
+	if _, err := c.GetPlayerStatus(context.Background(), "lv123456789"); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+}
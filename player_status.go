@@ -2,24 +2,22 @@ package nico
 
 import (
 	"context"
+	"encoding/json"
 	"encoding/xml"
-	"errors"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 // PlayerStatus is niconico live player status.
 type PlayerStatus struct {
-	Status string `xml:"status,attr"`
-	Time   int64  `xml:"time,attr"`
-	Stream Stream `xml:"stream"`
-	User   User   `xml:"user"`
-	Rtmp   Rtmp   `xml:"rtmp"`
-	Ms     Ms     `xml:"ms"`
-
-	// TODO
-	TidList interface{} `xml:"tid_list"`
-
+	Status  string  `xml:"status,attr"`
+	Time    int64   `xml:"time,attr"`
+	Stream  Stream  `xml:"stream"`
+	User    User    `xml:"user"`
+	Rtmp    Rtmp    `xml:"rtmp"`
+	Ms      Ms      `xml:"ms"`
+	TidList TidList `xml:"tid_list"`
 	Twitter Twitter `xml:"twitter"`
 	Player  Player  `xml:"player"`
 	Marquee Marquee `xml:"marquee"`
@@ -28,73 +26,80 @@ type PlayerStatus struct {
 
 // Stream is niconico live player status in player status.
 type Stream struct {
-	ID                       string `xml:"id"`
-	Title                    string `xml:"title"`
-	Description              string `xml:"description"`
-	ProviderType             string `xml:"provider_type"`
-	DefaultCommunity         string `xml:"default_community"`
-	International            int64  `xml:"international"`
-	IsOwner                  int64  `xml:"is_owner"`
-	OwnerID                  int64  `xml:"owner_id"`
-	OwnerName                string `xml:"owner_name"`
-	IsReserved               int64  `xml:"is_reserved"`
-	IsNiconicoEnqueteEnabled int64  `xml:"is_niconico_enquete_enabled"`
-	WatchCount               int64  `xml:"watch_count"`
-	CommentCount             int64  `xml:"comment_count"`
-	BaseTime                 int64  `xml:"base_time"`
-	OpenTime                 int64  `xml:"open_time"`
-	StartTime                int64  `xml:"start_time"`
-	EndTime                  int64  `xml:"end_time"`
-	IsRerunStream            int64  `xml:"is_rerun_stream"`
-
-	// TODO
-	BourbonURL   interface{} `xml:"bourbon_url"`
-	FullVideo    interface{} `xml:"full_video"`
-	AfterVideo   interface{} `xml:"after_video"`
-	BeforeVideo  interface{} `xml:"before_video"`
-	KickoutVideo interface{} `xml:"kickout_video"`
-
-	TwitterTag       string `xml:"twitter_tag"`
-	DanjoCommentMode int64  `xml:"danjo_comment_mode"`
-	InfinityMode     int64  `xml:"infinity_mode"`
-	Archive          int64  `xml:"archive"`
-	Press            Press  `xml:"press"`
-
-	// TODO
-	PluginDelay interface{} `xml:"plugin_delay"`
-	PluginURL   interface{} `xml:"plugin_url"`
-	PluginURLs  interface{} `xml:"plugin_urls"`
-
-	AllowNetduetto               int64 `xml:"allow_netduetto"`
-	NgScoring                    int64 `xml:"ng_scoring"`
-	IsNonarchiveTimeshiftEnabled int64 `xml:"is_nonarchive_timeshift_enabled"`
-	IsTimeshiftReserved          int64 `xml:"is_timeshift_reserved"`
-	HeaderComment                int64 `xml:"header_comment"`
-	FooterComment                int64 `xml:"footer_comment"`
-	SplitBottom                  int64 `xml:"split_bottom"`
-	SplitTop                     int64 `xml:"split_top"`
-	BackgroundComment            int64 `xml:"background_comment"`
-
-	// TODO
-	FontScale interface{} `xml:"font_scale"`
-
-	CommentLock  int64        `xml:"comment_lock"`
+	ID                       string   `xml:"id"`
+	Title                    string   `xml:"title"`
+	Description              string   `xml:"description"`
+	ProviderType             string   `xml:"provider_type"`
+	DefaultCommunity         string   `xml:"default_community"`
+	International            int64    `xml:"international"`
+	IsOwner                  Flag     `xml:"is_owner"`
+	OwnerID                  int64    `xml:"owner_id"`
+	OwnerName                string   `xml:"owner_name"`
+	IsReserved               int64    `xml:"is_reserved"`
+	IsNiconicoEnqueteEnabled int64    `xml:"is_niconico_enquete_enabled"`
+	WatchCount               int64    `xml:"watch_count"`
+	CommentCount             int64    `xml:"comment_count"`
+	BaseTime                 UnixTime `xml:"base_time"`
+	OpenTime                 UnixTime `xml:"open_time"`
+	StartTime                UnixTime `xml:"start_time"`
+	EndTime                  UnixTime `xml:"end_time"`
+	IsRerunStream            Flag     `xml:"is_rerun_stream"`
+	BourbonURL               string   `xml:"bourbon_url"`
+	FullVideo                string   `xml:"full_video"`
+	AfterVideo               string   `xml:"after_video"`
+	BeforeVideo              string   `xml:"before_video"`
+	KickoutVideo             string   `xml:"kickout_video"`
+	TwitterTag               string   `xml:"twitter_tag"`
+	DanjoCommentMode         int64    `xml:"danjo_comment_mode"`
+	InfinityMode             int64    `xml:"infinity_mode"`
+	Archive                  Flag     `xml:"archive"`
+	Press                    Press    `xml:"press"`
+
+	PluginDelay int64      `xml:"plugin_delay"`
+	PluginURL   string     `xml:"plugin_url"`
+	PluginURLs  PluginURLs `xml:"plugin_urls"`
+
+	AllowNetduetto               int64   `xml:"allow_netduetto"`
+	NgScoring                    int64   `xml:"ng_scoring"`
+	IsNonarchiveTimeshiftEnabled int64   `xml:"is_nonarchive_timeshift_enabled"`
+	IsTimeshiftReserved          int64   `xml:"is_timeshift_reserved"`
+	HeaderComment                int64   `xml:"header_comment"`
+	FooterComment                int64   `xml:"footer_comment"`
+	SplitBottom                  int64   `xml:"split_bottom"`
+	SplitTop                     int64   `xml:"split_top"`
+	BackgroundComment            int64   `xml:"background_comment"`
+	FontScale                    float64 `xml:"font_scale"`
+	CommentLock                  Flag    `xml:"comment_lock"`
+
 	Telop        Telop        `xml:"telop"`
 	ContentsList ContentsList `xml:"contents_list"`
 	PictureURL   string       `xml:"picture_url"`
 	ThumbURL     string       `xml:"thumb_url"`
 
-	// TODO
-	IsPriorityPrefecture interface{} `xml:"is_priority_prefecture"`
+	IsPriorityPrefecture string `xml:"is_priority_prefecture"`
+}
+
+// TidList is a list of the thread IDs.
+type TidList struct {
+	Tids []int64 `xml:"tid"`
+}
+
+// PluginURLs is a list of the URLs of the plugins.
+type PluginURLs struct {
+	URLs []string `xml:"plugin_url"`
 }
 
 // Press is unknown data.
 type Press struct {
-	DisplayLines int64 `xml:"display_lines"`
-	DisplayTime  int64 `xml:"display_time"`
+	DisplayLines int64     `xml:"display_lines"`
+	DisplayTime  int64     `xml:"display_time"`
+	StyleConf    StyleConf `xml:"style_conf"`
+}
 
-	// TODO
-	StyleConf interface{} `xml:"style_conf"`
+// StyleConf is the style of the press comment.
+// The format of the content is undocumented so it is kept as is.
+type StyleConf struct {
+	InnerXML string `xml:",innerxml"`
 }
 
 // Telop is unknown data.
@@ -102,10 +107,9 @@ type Telop struct {
 	Enable int64 `xml:"enable"`
 }
 
-// ContentsList is a list of contents.
+// ContentsList is a list of contents such as main and sub.
 type ContentsList struct {
-	// TODO slice?
-	Contents Contents `xml:"contents"`
+	Contents []Contents `xml:"contents"`
 }
 
 // Contents is detailed information of contents such as URL of RTMP etc.
@@ -121,7 +125,7 @@ type Contents struct {
 type User struct {
 	UserID         int64  `xml:"user_id"`
 	Nickname       string `xml:"nickname"`
-	IsPremium      int64  `xml:"is_premium"`
+	IsPremium      Flag   `xml:"is_premium"`
 	UserAge        int64  `xml:"userAge"`
 	UserSex        int64  `xml:"userSex"`
 	UserDomain     string `xml:"userDomain"`
@@ -130,21 +134,21 @@ type User struct {
 	RoomLabel      string `xml:"room_label"`
 	RoomSeetno     int64  `xml:"room_seetno"`
 
-	// TODO
-	IsJoin interface{} `xml:"is_join"`
+	// IsJoin is set if the user is a member of the community.
+	IsJoin Flag `xml:"is_join"`
 
 	TwitterInfo TwitterInfo `xml:"twitter_info"`
 }
 
 // TwitterInfo is user's twitter info in user.
 type TwitterInfo struct {
-	Status          string      `xml:"status"`
-	ScreenName      interface{} `xml:"screen_name"`
-	FollowersCount  int64       `xml:"followers_count"`
-	IsVip           int64       `xml:"is_vip"`
-	ProfileImageURL string      `xml:"profile_image_url"`
-	AfterAuth       int64       `xml:"after_auth"`
-	TweetToken      string      `xml:"tweet_token"`
+	Status          string `xml:"status"`
+	ScreenName      string `xml:"screen_name"`
+	FollowersCount  int64  `xml:"followers_count"`
+	IsVip           int64  `xml:"is_vip"`
+	ProfileImageURL string `xml:"profile_image_url"`
+	AfterAuth       int64  `xml:"after_auth"`
+	TweetToken      string `xml:"tweet_token"`
 }
 
 // Rtmp is information on RTMP.
@@ -195,8 +199,176 @@ type Error struct {
 	Code string `xml:"code"`
 }
 
+// Flag is a boolean sent as an integer such as 0 or 1.
+// It is marshaled to JSON as a boolean.
+type Flag int64
+
+// Bool reports whether the flag is set.
+func (f Flag) Bool() bool {
+	return f != 0
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (f Flag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Bool())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It accepts a boolean or an integer.
+func (f *Flag) UnmarshalJSON(b []byte) error {
+	var v bool
+	if err := json.Unmarshal(b, &v); err == nil {
+		*f = 0
+		if v {
+			*f = 1
+		}
+		return nil
+	}
+	var n int64
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*f = Flag(n)
+	return nil
+}
+
+// UnixTime is a time sent as unix seconds. Zero means the time is not set.
+// It is marshaled to JSON as a RFC 3339 string, or null if it is not set.
+type UnixTime int64
+
+// Time returns the time. It returns the zero time.Time if t is not set.
+func (t UnixTime) Time() time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(t), 0)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (t UnixTime) MarshalJSON() ([]byte, error) {
+	if t == 0 {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Time())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It accepts null, a RFC 3339 string or unix seconds.
+func (t *UnixTime) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*t = 0
+		return nil
+	}
+	var n int64
+	if err := json.Unmarshal(b, &n); err == nil {
+		*t = UnixTime(n)
+		return nil
+	}
+	var tt time.Time
+	if err := json.Unmarshal(b, &tt); err != nil {
+		return err
+	}
+	*t = UnixTime(tt.Unix())
+	return nil
+}
+
+// IsOwner reports whether the user is the broadcaster.
+func (ps *PlayerStatus) IsOwner() bool {
+	return ps.Stream.IsOwner.Bool()
+}
+
+// IsPremium reports whether the user is a premium member.
+func (ps *PlayerStatus) IsPremium() bool {
+	return ps.User.IsPremium.Bool()
+}
+
+// IsJoin reports whether the user is a member of the community.
+func (ps *PlayerStatus) IsJoin() bool {
+	return ps.User.IsJoin.Bool()
+}
+
+// IsArchive reports whether the broadcast can be watched as timeshift.
+func (ps *PlayerStatus) IsArchive() bool {
+	return ps.Stream.Archive.Bool()
+}
+
+// IsCommentLocked reports whether posting comments is locked.
+func (ps *PlayerStatus) IsCommentLocked() bool {
+	return ps.Stream.CommentLock.Bool()
+}
+
+// IsRerun reports whether the broadcast is a rerun.
+func (ps *PlayerStatus) IsRerun() bool {
+	return ps.Stream.IsRerunStream.Bool()
+}
+
+// BaseAt returns the base time of vpos of the comments.
+func (ps *PlayerStatus) BaseAt() time.Time {
+	return ps.Stream.BaseTime.Time()
+}
+
+// OpenAt returns the time the doors open.
+func (ps *PlayerStatus) OpenAt() time.Time {
+	return ps.Stream.OpenTime.Time()
+}
+
+// StartAt returns the time the broadcast starts.
+func (ps *PlayerStatus) StartAt() time.Time {
+	return ps.Stream.StartTime.Time()
+}
+
+// EndAt returns the time the broadcast is scheduled to end.
+func (ps *PlayerStatus) EndAt() time.Time {
+	return ps.Stream.EndTime.Time()
+}
+
+// Elapsed returns the elapsed time of the broadcast at now.
+// It returns 0 before the start.
+func (ps *PlayerStatus) Elapsed(now time.Time) time.Duration {
+	if ps.Stream.StartTime == 0 || now.Before(ps.StartAt()) {
+		return 0
+	}
+	return now.Sub(ps.StartAt())
+}
+
+// Remaining returns the remaining time of the broadcast at now.
+// It returns 0 after the end or if the end time is not set.
+func (ps *PlayerStatus) Remaining(now time.Time) time.Duration {
+	if ps.Stream.EndTime == 0 || !now.Before(ps.EndAt()) {
+		return 0
+	}
+	return ps.EndAt().Sub(now)
+}
+
+// IsOnAir reports whether the broadcast is on air at now.
+// The broadcast is regarded as not ended if the end time is not set.
+func (ps *PlayerStatus) IsOnAir(now time.Time) bool {
+	if ps.Stream.StartTime == 0 || now.Before(ps.StartAt()) {
+		return false
+	}
+	return ps.Stream.EndTime == 0 || now.Before(ps.EndAt())
+}
+
+// vpos returns the playback position of the comment posted at now
+// in 1/100 seconds.
+func (ps *PlayerStatus) vpos(now time.Time) int64 {
+	return int64(now.Sub(ps.BaseAt()) / (10 * time.Millisecond))
+}
+
 // GetPlayerStatus gets the player status.
+// If the session is expired and the client has the CredentialsProvider,
+// it logins again and retries once.
 func (c *Client) GetPlayerStatus(ctx context.Context, liveID string) (*PlayerStatus, error) {
+	var ps *PlayerStatus
+	err := c.withRelogin(ctx, func() error {
+		var err error
+		ps, err = c.getPlayerStatus(ctx, liveID)
+		return err
+	})
+	return ps, err
+}
+
+func (c *Client) getPlayerStatus(ctx context.Context, liveID string) (*PlayerStatus, error) {
 	u, err := url.Parse(c.liveBaseRawurl)
 	if err != nil {
 		return nil, err
@@ -207,20 +379,18 @@ func (c *Client) GetPlayerStatus(ctx context.Context, liveID string) (*PlayerSta
 	v.Set("v", liveID)
 	u.RawQuery = v.Encode()
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := c.newAuthRequest(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
-	req.AddCookie(&http.Cookie{Name: "user_session", Value: c.UserSession})
 
-	resp, err := c.Do(req)
+	resp, err := c.do(req, EndpointGetPlayerStatus)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(resp.Status)
+		return nil, newAPIError(EndpointGetPlayerStatus, resp)
 	}
 
 	ps := PlayerStatus{}
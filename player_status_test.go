@@ -0,0 +1,168 @@
+package nico
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newPlayerStatusServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/getplayerstatus.xml")
+	}))
+}
+
+func TestGetPlayerStatus_Fixture(t *testing.T) {
+	ts := newPlayerStatusServer(t)
+	defer ts.Close()
+
+	c := NewClient(WithLiveBaseURL(ts.URL))
+	ps, err := c.GetPlayerStatus(context.Background(), "lv301234567")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"TidList", ps.TidList.Tids, []int64{1234567890, 1234567891}},
+		{"BourbonURL", ps.Stream.BourbonURL, "http://live.nicovideo.jp/gate/lv301234567?sec=nicolive_crowded&sub=watch_crowded_0_community_lv301234567_onair"},
+		{"FullVideo", ps.Stream.FullVideo, "http://live.nicovideo.jp/gate/lv301234567?sec=nicolive_crowded&sub=watch_crowded_0_community_lv301234567_onair"},
+		{"AfterVideo", ps.Stream.AfterVideo, "http://live.nicovideo.jp/after.flv"},
+		{"BeforeVideo", ps.Stream.BeforeVideo, "http://live.nicovideo.jp/before.flv"},
+		{"KickoutVideo", ps.Stream.KickoutVideo, "http://live.nicovideo.jp/gate/lv301234567?sec=nicolive_oidashi&sub=watchplayer_oidashialert_0_community_lv301234567_onair"},
+		{"PluginDelay", ps.Stream.PluginDelay, int64(3)},
+		{"PluginURL", ps.Stream.PluginURL, "http://live.nicovideo.jp/plugin.swf"},
+		{"PluginURLs", ps.Stream.PluginURLs.URLs, []string{"http://live.nicovideo.jp/plugin1.swf", "http://live.nicovideo.jp/plugin2.swf"}},
+		{"FontScale", ps.Stream.FontScale, 1.5},
+		{"IsPriorityPrefecture", ps.Stream.IsPriorityPrefecture, "13"},
+		{"StyleConf", ps.Stream.Press.StyleConf.InnerXML, "<style>bold</style>"},
+		{"IsJoin", ps.User.IsJoin, Flag(1)},
+		{"ScreenName", ps.User.TwitterInfo.ScreenName, "foo_twitter"},
+		{"Ms", ps.Ms, Ms{Addr: "omsg103.live.nicovideo.jp", Port: 2815, Thread: 1234567890}},
+		{"Marquee.Category", ps.Marquee.Category, "一般(その他)"},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s: want %#v but %#v", tt.name, tt.want, tt.got)
+		}
+	}
+}
+
+func TestGetPlayerStatus_EmptyFields(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?><getplayerstatus status="ok"><stream><plugin_delay></plugin_delay><plugin_url/><plugin_urls/><font_scale></font_scale><is_priority_prefecture></is_priority_prefecture><press><style_conf/></press></stream><user><is_join></is_join><twitter_info><screen_name></screen_name></twitter_info></user><tid_list/></getplayerstatus>`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithLiveBaseURL(ts.URL))
+	ps, err := c.GetPlayerStatus(context.Background(), "lv301234567")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if len(ps.TidList.Tids) != 0 || len(ps.Stream.PluginURLs.URLs) != 0 {
+		t.Fatalf("lists should be empty: %v, %v", ps.TidList.Tids, ps.Stream.PluginURLs.URLs)
+	}
+}
+
+func TestPlayerStatus_Accessors(t *testing.T) {
+	ps := &PlayerStatus{
+		Stream: Stream{
+			IsOwner:   1,
+			BaseTime:  1499999940,
+			OpenTime:  1499999940,
+			StartTime: 1500000000,
+			EndTime:   1500007200,
+		},
+		User: User{IsPremium: 1},
+	}
+	if !ps.IsOwner() {
+		t.Fatalf("should be owner")
+	}
+	if !ps.IsPremium() {
+		t.Fatalf("should be premium")
+	}
+	if ps.IsJoin() {
+		t.Fatalf("should not be joined")
+	}
+	if want := time.Unix(1500000000, 0); !ps.StartAt().Equal(want) {
+		t.Fatalf("want %v but %v", want, ps.StartAt())
+	}
+
+	tests := []struct {
+		now       time.Time
+		elapsed   time.Duration
+		remaining time.Duration
+		onAir     bool
+	}{
+		{time.Unix(1499999970, 0), 0, 7230 * time.Second, false},
+		{time.Unix(1500000000, 0), 0, 2 * time.Hour, true},
+		{time.Unix(1500003600, 0), time.Hour, time.Hour, true},
+		{time.Unix(1500007200, 0), 2 * time.Hour, 0, false},
+	}
+	for _, tt := range tests {
+		if got := ps.Elapsed(tt.now); got != tt.elapsed {
+			t.Errorf("Elapsed(%v): want %v but %v", tt.now, tt.elapsed, got)
+		}
+		if got := ps.Remaining(tt.now); got != tt.remaining {
+			t.Errorf("Remaining(%v): want %v but %v", tt.now, tt.remaining, got)
+		}
+		if got := ps.IsOnAir(tt.now); got != tt.onAir {
+			t.Errorf("IsOnAir(%v): want %v but %v", tt.now, tt.onAir, got)
+		}
+	}
+
+	if got := ps.vpos(time.Unix(1500000000, 0).Add(1234 * time.Millisecond)); got != 6123 {
+		t.Fatalf("want %d but %d", 6123, got)
+	}
+
+	ps.Stream.EndTime = 0
+	if !ps.IsOnAir(time.Unix(1600000000, 0)) {
+		t.Fatalf("should be on air if the end time is not set")
+	}
+	if got := ps.Remaining(time.Unix(1500000000, 0)); got != 0 {
+		t.Fatalf("want %v but %v", time.Duration(0), got)
+	}
+}
+
+func TestPlayerStatus_JSON(t *testing.T) {
+	s := Stream{IsOwner: 1, Archive: 0, StartTime: 1500000000, DanjoCommentMode: 2}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if m["IsOwner"] != true || m["Archive"] != false {
+		t.Fatalf("flags should be marshaled as bool: %s", b)
+	}
+	if want := time.Unix(1500000000, 0).Format(time.RFC3339); m["StartTime"] != want {
+		t.Fatalf("want %q but %q", want, m["StartTime"])
+	}
+	if m["EndTime"] != nil {
+		t.Fatalf("unset time should be null: %v", m["EndTime"])
+	}
+
+	var got Stream
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if got.IsOwner != 1 || got.Archive != 0 || got.StartTime != 1500000000 || got.EndTime != 0 || got.DanjoCommentMode != 2 {
+		t.Fatalf("want %+v but %+v", s, got)
+	}
+
+	if err := json.Unmarshal([]byte(`{"IsOwner":1,"StartTime":1500000000}`), &got); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if !got.IsOwner.Bool() || got.StartTime != 1500000000 {
+		t.Fatalf("integers should be accepted: %+v", got)
+	}
+}
@@ -0,0 +1,176 @@
+package nico
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultCooldown is the default duration that a throttled account is not handed out.
+const DefaultCooldown = time.Minute
+
+// PoolStrategy is a strategy of AccountPool to choose an account.
+type PoolStrategy int
+
+// Strategies of AccountPool.
+const (
+	PoolRoundRobin PoolStrategy = iota
+	PoolLeastRecentlyUsed
+)
+
+// PoolOption is a function that configures an AccountPool.
+type PoolOption func(*AccountPool)
+
+// WithPoolStrategy sets the strategy to choose an account.
+func WithPoolStrategy(s PoolStrategy) PoolOption {
+	return func(p *AccountPool) { p.strategy = s }
+}
+
+// WithCooldown sets the duration that a throttled account is not handed out.
+func WithCooldown(d time.Duration) PoolOption {
+	return func(p *AccountPool) { p.cooldown = d }
+}
+
+// AccountPool is a pool of the logged-in clients of several accounts.
+// AccountPool is safe for concurrent use by multiple goroutines.
+type AccountPool struct {
+	mu       sync.Mutex
+	accounts []*poolAccount
+	strategy PoolStrategy
+	cooldown time.Duration
+	next     int
+	now      func() time.Time
+}
+
+type poolAccount struct {
+	client    *Client
+	lastUsed  time.Time
+	coolUntil time.Time
+}
+
+// NewAccountPool returns new AccountPool of clients.
+func NewAccountPool(clients []*Client, opts ...PoolOption) *AccountPool {
+	p := &AccountPool{cooldown: DefaultCooldown, now: time.Now}
+	for _, opt := range opts {
+		opt(p)
+	}
+	for _, c := range clients {
+		p.Add(c)
+	}
+	return p
+}
+
+// Add adds c to the pool.
+func (p *AccountPool) Add(c *Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.accounts = append(p.accounts, &poolAccount{client: c})
+}
+
+// Len returns the number of the accounts in the pool.
+func (p *AccountPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.accounts)
+}
+
+// Get returns a client that is not cooling down.
+// It returns ErrNoAccountAvailable if all accounts are cooling down.
+func (p *AccountPool) Get() (*Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var chosen *poolAccount
+	switch p.strategy {
+	case PoolLeastRecentlyUsed:
+		for _, a := range p.accounts {
+			if a.coolUntil.After(now) {
+				continue
+			}
+			if chosen == nil || a.lastUsed.Before(chosen.lastUsed) {
+				chosen = a
+			}
+		}
+	default:
+		for i := range p.accounts {
+			a := p.accounts[(p.next+i)%len(p.accounts)]
+			if !a.coolUntil.After(now) {
+				chosen = a
+				p.next = (p.next + i + 1) % len(p.accounts)
+				break
+			}
+		}
+	}
+	if chosen == nil {
+		return nil, ErrNoAccountAvailable
+	}
+	chosen.lastUsed = now
+	return chosen.client, nil
+}
+
+// MarkCoolingDown stops handing out c for the cooldown of the pool.
+func (p *AccountPool) MarkCoolingDown(c *Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, a := range p.accounts {
+		if a.client == c {
+			a.coolUntil = p.now().Add(p.cooldown)
+		}
+	}
+}
+
+// Report marks c as cooling down if err is caused by throttling
+// such as full seats, too many requests or rejected posts.
+// The error of the post is returned by ChatResult.Err.
+func (p *AccountPool) Report(c *Client, err error) {
+	if isThrottled(err) {
+		p.MarkCoolingDown(c)
+	}
+}
+
+// MakeLiveClient creates a LiveClient of liveID with an account chosen from the pool.
+// The throttled account is marked as cooling down and the next account is tried.
+func (p *AccountPool) MakeLiveClient(ctx context.Context, liveID string) (*LiveClient, error) {
+	var lastErr error
+	for i := p.Len(); i > 0; i-- {
+		c, err := p.Get()
+		if err != nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, err
+		}
+		lc, err := c.MakeLiveClient(ctx, liveID)
+		if err == nil {
+			return lc, nil
+		}
+		if !isThrottled(err) {
+			return nil, err
+		}
+		p.MarkCoolingDown(c)
+		lastErr = err
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, ErrNoAccountAvailable
+}
+
+func isThrottled(err error) bool {
+	// getpostkey returns the empty postkey when it is called too often.
+	if errors.Is(err, ErrSeatsFull) || errors.Is(err, ErrPostkeyEmpty) {
+		return true
+	}
+	var crErr *ChatResultError
+	if errors.As(err, &crErr) {
+		return crErr.Status == ChatResultFailure
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusServiceUnavailable
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package nico
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccountPool_Get(t *testing.T) {
+	c1, c2, c3 := &Client{}, &Client{}, &Client{}
+	now := time.Unix(1500000000, 0)
+	p := NewAccountPool([]*Client{c1, c2, c3}, WithCooldown(time.Minute))
+	p.now = func() time.Time { return now }
+
+	for _, want := range []*Client{c1, c2, c3, c1} {
+		got, err := p.Get()
+		if err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+		if got != want {
+			t.Fatalf("want %p but %p", want, got)
+		}
+	}
+
+	p.MarkCoolingDown(c2)
+	p.Report(c3, ErrSeatsFull)
+	p.Report(c1, ErrLoginFailed)
+	p.Report(c1, (&ChatResult{Status: ChatResultInvalidPostkey}).Err())
+	for i := 0; i < 2; i++ {
+		if got, _ := p.Get(); got != c1 {
+			t.Fatalf("want %p but %p", c1, got)
+		}
+	}
+	p.MarkCoolingDown(c1)
+	if _, err := p.Get(); err != ErrNoAccountAvailable {
+		t.Fatalf("want %v but %v", ErrNoAccountAvailable, err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := p.Get(); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	// The rejected post is throttling.
+	p.Report(c1, (&ChatResult{Status: ChatResultFailure}).Err())
+	p.Report(c2, ErrPostkeyEmpty)
+	for i := 0; i < 2; i++ {
+		if got, _ := p.Get(); got != c3 {
+			t.Fatalf("want %p but %p", c3, got)
+		}
+	}
+}
+
+func TestAccountPool_LeastRecentlyUsed(t *testing.T) {
+	c1, c2 := &Client{}, &Client{}
+	now := time.Unix(1500000000, 0)
+	p := NewAccountPool([]*Client{c1, c2}, WithPoolStrategy(PoolLeastRecentlyUsed))
+	p.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for _, want := range []*Client{c1, c2, c1, c2} {
+		if got, _ := p.Get(); got != want {
+			t.Fatalf("want %p but %p", want, got)
+		}
+	}
+}
+
+func TestAccountPool_MakeLiveClient(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	defer ln.Close()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		us, _ := r.Cookie("user_session")
+		if us.Value == "full" {
+			io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><getplayerstatus status="fail"><error><code>full</code></error></getplayerstatus>`)
+			return
+		}
+		io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><getplayerstatus status="ok"><ms><addr>`+host+`</addr><port>`+port+`</port><thread>1</thread></ms></getplayerstatus>`)
+	}))
+	defer ts.Close()
+
+	c1 := &Client{liveBaseRawurl: ts.URL, UserSession: "full"}
+	c2 := &Client{liveBaseRawurl: ts.URL, UserSession: "ok"}
+	p := NewAccountPool([]*Client{c1, c2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lc, err := p.MakeLiveClient(ctx, "lv123456789")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if lc.Client != c2 {
+		t.Fatalf("want %p but %p", c2, lc.Client)
+	}
+	if got, _ := p.Get(); got != c2 {
+		t.Fatalf("full account should be cooling down")
+	}
+
+	p = NewAccountPool([]*Client{c1})
+	if _, err := p.MakeLiveClient(ctx, "lv123456789"); err == nil || !strings.Contains(err.Error(), "full") {
+		t.Fatalf("want full error but %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package nico
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket setting of requests to a host.
+type RateLimit struct {
+	// Rate is the number of requests allowed per second.
+	Rate float64
+
+	// Burst is the maximum number of requests sent at once.
+	Burst int
+}
+
+// RateLimitStats is statistics of the rate limiter of a host.
+type RateLimitStats struct {
+	Requests int64
+	Waits    int64
+	WaitTime time.Duration
+	Tokens   float64
+}
+
+// WithRateLimit limits the requests to host by token bucket.
+// The requests wait for a token until the context of the request is done.
+func WithRateLimit(host string, l RateLimit) Option {
+	return func(c *Client) {
+		if c.limiters == nil {
+			c.limiters = map[string]*limiter{}
+		}
+		c.limiters[strings.ToLower(host)] = newLimiter(l)
+	}
+}
+
+// RateLimitStats returns the statistics of the rate limiters by host.
+func (c *Client) RateLimitStats() map[string]RateLimitStats {
+	stats := make(map[string]RateLimitStats, len(c.limiters))
+	for host, l := range c.limiters {
+		stats[host] = l.stats(time.Now())
+	}
+	return stats
+}
+
+func (c *Client) waitRateLimit(ctx context.Context, host string) error {
+	l, ok := c.limiters[strings.ToLower(host)]
+	if !ok {
+		return nil
+	}
+	return l.wait(ctx)
+}
+
+type limiter struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	requests int64
+	waits    int64
+	waitTime time.Duration
+}
+
+func newLimiter(l RateLimit) *limiter {
+	burst := float64(l.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{rate: l.Rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+func (l *limiter) refill(now time.Time) {
+	if l.rate > 0 && now.After(l.last) {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
+
+// reserve takes a token and returns the delay until the token is available.
+func (l *limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(now)
+	l.requests++
+	l.tokens--
+	if l.tokens >= 0 || l.rate <= 0 {
+		return 0
+	}
+	d := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.waits++
+	l.waitTime += d
+	return d
+}
+
+// cancel returns the reserved token.
+func (l *limiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests--
+	l.tokens++
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+func (l *limiter) wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := sleep(ctx, l.reserve(time.Now())); err != nil {
+		l.cancel()
+		return err
+	}
+	return nil
+}
+
+func (l *limiter) stats(now time.Time) RateLimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(now)
+	return RateLimitStats{
+		Requests: l.requests,
+		Waits:    l.waits,
+		WaitTime: l.waitTime,
+		Tokens:   l.tokens,
+	}
+}
@@ -0,0 +1,65 @@
+package nico
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/leave/co1234567" {
+			io.WriteString(w, `<form class="leave_form"><input name="commit" value="yes"></form>`)
+			return
+		}
+		io.WriteString(w, "postkey=foo")
+	}))
+	defer ts.Close()
+
+	c := NewClient(
+		WithLiveBaseURL(ts.URL),
+		WithCommunityBaseURL(ts.URL),
+		WithRateLimit("127.0.0.1", RateLimit{Rate: 20, Burst: 2}),
+	)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetPostkey(ctx, 1); err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+	}
+	if _, err := c.getLeaveCommunityFormData(ctx, "co1234567"); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Fatalf("should be limited: %v", d)
+	}
+
+	stats := c.RateLimitStats()["127.0.0.1"]
+	if stats.Requests != 4 {
+		t.Fatalf("want %d but %d", 4, stats.Requests)
+	}
+	if stats.Waits != 2 {
+		t.Fatalf("want %d but %d", 2, stats.Waits)
+	}
+	if stats.WaitTime <= 0 {
+		t.Fatalf("WaitTime should be positive: %v", stats.WaitTime)
+	}
+}
+
+func TestRateLimit_Context(t *testing.T) {
+	c := NewClient(WithLiveBaseURL("http://127.0.0.1:1"), WithRateLimit("127.0.0.1", RateLimit{Rate: 0.1, Burst: 1}))
+	c.limiters["127.0.0.1"].tokens = 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetPostkey(ctx, 1); err != context.DeadlineExceeded {
+		t.Fatalf("want %v but %v", context.DeadlineExceeded, err)
+	}
+	if stats := c.RateLimitStats()["127.0.0.1"]; stats.Requests != 0 || stats.Tokens < 0 {
+		t.Fatalf("reserved token should be returned: %+v", stats)
+	}
+}
@@ -0,0 +1,116 @@
+package nico
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryPolicy is a recommended RetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// RetryPolicy is a policy to retry idempotent API calls
+// that failed with a network error or a 429 or 5xx response.
+// Login, FollowCommunity and LeaveCommunity are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	// The call is not retried if it is less than 2.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry.
+	// The delay doubles on every retry with jitter.
+	BaseDelay time.Duration
+
+	// MaxDelay is the upper limit of the delay.
+	// Retry-After of the response is respected even if it is longer.
+	MaxDelay time.Duration
+
+	// DisabledEndpoints is the endpoints that are not retried.
+	DisabledEndpoints []Endpoint
+}
+
+// WithRetryPolicy sets the retry policy of idempotent API calls.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retryPolicy = p }
+}
+
+func (p RetryPolicy) enabled(req *http.Request, ep Endpoint) bool {
+	if p.MaxAttempts < 2 {
+		return false
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	for _, dep := range p.DisabledEndpoints {
+		if dep == ep {
+			return false
+		}
+	}
+	return true
+}
+
+// backoff returns the delay before the retry of attempt (1-origin).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// retryAfter parses Retry-After header of resp.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,132 @@
+package nico
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&n, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><getplayerstatus status="ok"></getplayerstatus>`)
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithLiveBaseURL(ts.URL), WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+	if _, err := c.GetPlayerStatus(context.Background(), "lv123456789"); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if got := atomic.LoadInt32(&n); got != 3 {
+		t.Fatalf("want %d but %d", 3, got)
+	}
+
+	atomic.StoreInt32(&n, 0)
+	c = NewClient(WithLiveBaseURL(ts.URL), WithRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}))
+	if _, err := c.GetPlayerStatus(context.Background(), "lv123456789"); err == nil {
+		t.Fatalf("should be fail: %v", err)
+	}
+	if got := atomic.LoadInt32(&n); got != 2 {
+		t.Fatalf("want %d but %d", 2, got)
+	}
+}
+
+func TestRetry_Disabled(t *testing.T) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	p := RetryPolicy{
+		MaxAttempts:       3,
+		BaseDelay:         time.Millisecond,
+		DisabledEndpoints: []Endpoint{EndpointGetPlayerStatus},
+	}
+	c := NewClient(WithLiveBaseURL(ts.URL), WithCommunityBaseURL(ts.URL), WithLoginURL(ts.URL), WithRetryPolicy(p))
+	ctx := context.Background()
+	if _, err := c.GetPlayerStatus(ctx, "lv123456789"); err == nil {
+		t.Fatalf("should be fail: %v", err)
+	}
+	if _, err := c.Login(ctx, "foo@foo.com", "bar"); err == nil {
+		t.Fatalf("should be fail: %v", err)
+	}
+	if err := c.FollowCommunity(ctx, "co1234567"); err == nil {
+		t.Fatalf("should be fail: %v", err)
+	}
+	if got := atomic.LoadInt32(&n); got != 3 {
+		t.Fatalf("want %d but %d", 3, got)
+	}
+}
+
+func TestRetry_RetryAfter(t *testing.T) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&n, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, "postkey=foo")
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithLiveBaseURL(ts.URL), WithRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}))
+	start := time.Now()
+	if _, err := c.GetPostkey(context.Background(), 1); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if d := time.Since(start); d < time.Second {
+		t.Fatalf("should wait for Retry-After: %v", d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	atomic.StoreInt32(&n, 0)
+	if _, err := c.GetPostkey(ctx, 1); err != context.DeadlineExceeded {
+		t.Fatalf("want %v but %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in string
+		d  time.Duration
+		ok bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"Sun, 01 Jan 2017 00:00:30 GMT", 30 * time.Second, true},
+		{"Sat, 31 Dec 2016 00:00:30 GMT", 0, true},
+		{"foo", 0, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Retry-After", tt.in)
+		d, ok := retryAfter(resp, now)
+		if d != tt.d || ok != tt.ok {
+			t.Fatalf("%q: want %v, %v but %v, %v", tt.in, tt.d, tt.ok, d, ok)
+		}
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	for attempt, max := range []time.Duration{100, 200, 300, 300} {
+		max *= time.Millisecond
+		d := p.backoff(attempt + 1)
+		if d < max/2 || d > max {
+			t.Fatalf("attempt %d: %v should be in [%v, %v]", attempt+1, d, max/2, max)
+		}
+	}
+}
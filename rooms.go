@@ -0,0 +1,357 @@
+package nico
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ranges of the ports and the numbers of the comment servers.
+// The rooms of a broadcast are assigned to them in order.
+const (
+	roomPortMin = 2805
+	roomPortMax = 2814
+	roomAddrMin = 101
+	roomAddrMax = 104
+)
+
+// RoomLabelArena is the label of the arena used if the label is unknown.
+const RoomLabelArena = "アリーナ"
+
+// mergeWindow is the time to wait for the comments of the other rooms
+// before sending a comment in StreamingRoomsComment.
+const mergeWindow = 500 * time.Millisecond
+
+var roomAddrRegexp = regexp.MustCompile(`^([a-z]*msg)(\d+)(\..+)$`)
+
+// Room is a comment room of the broadcast such as the arena and the standing rooms.
+type Room struct {
+	Label  string
+	Addr   string
+	Port   int64
+	Thread int64
+}
+
+// Next returns the address, the port and the thread of the next room.
+// The label is not set. ok is false if the port of r is out of the ports of the rooms.
+func (r Room) Next() (n Room, ok bool) {
+	if !r.inRange() {
+		return Room{}, false
+	}
+	n = Room{Addr: r.Addr, Port: r.Port + 1, Thread: r.Thread + 1}
+	if n.Port > roomPortMax {
+		n.Port = roomPortMin
+		n.Addr = shiftRoomAddr(r.Addr, 1)
+	}
+	return n, true
+}
+
+// Prev returns the address, the port and the thread of the previous room.
+// The label is not set. ok is false if the port of r is out of the ports of the rooms.
+func (r Room) Prev() (p Room, ok bool) {
+	if !r.inRange() {
+		return Room{}, false
+	}
+	p = Room{Addr: r.Addr, Port: r.Port - 1, Thread: r.Thread - 1}
+	if p.Port < roomPortMin {
+		p.Port = roomPortMax
+		p.Addr = shiftRoomAddr(r.Addr, -1)
+	}
+	return p, true
+}
+
+func (r Room) inRange() bool {
+	return r.Port >= roomPortMin && r.Port <= roomPortMax
+}
+
+// shiftRoomAddr shifts the number of the comment server such as msg101 by d.
+// addr is returned as is if it is not the comment server of niconico live.
+func shiftRoomAddr(addr string, d int) string {
+	m := roomAddrRegexp.FindStringSubmatch(addr)
+	if m == nil {
+		return addr
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return addr
+	}
+	size := roomAddrMax - roomAddrMin + 1
+	n = roomAddrMin + ((n-roomAddrMin+d)%size+size)%size
+	return fmt.Sprintf("%s%d%s", m[1], n, m[3])
+}
+
+// roomIndex returns the index of the room of label.
+// The arena is 0 and the standing room A is 1.
+func roomIndex(label string) int {
+	if !strings.HasPrefix(label, "立ち見") || !strings.HasSuffix(label, "列") {
+		return 0
+	}
+	l := strings.TrimSuffix(strings.TrimPrefix(label, "立ち見"), "列")
+	if len(l) != 1 || l[0] < 'A' || l[0] > 'Z' {
+		return 0
+	}
+	return int(l[0]-'A') + 1
+}
+
+func roomLabel(arena string, i int) string {
+	if i == 0 {
+		return arena
+	}
+	return fmt.Sprintf("立ち見%c列", 'A'+i-1)
+}
+
+// Room returns the room assigned to the user.
+func (ps *PlayerStatus) Room() Room {
+	return Room{
+		Label:  ps.User.RoomLabel,
+		Addr:   ps.Ms.Addr,
+		Port:   ps.Ms.Port,
+		Thread: ps.Ms.Thread,
+	}
+}
+
+// Rooms returns the arena and standing rooms derived from the assigned room.
+// The assigned room is always included even if it is over standing.
+// Only the assigned room is returned if the other rooms cannot be derived from its port.
+func (ps *PlayerStatus) Rooms(standing int) []Room {
+	assigned := ps.Room()
+	if !assigned.inRange() {
+		return []Room{assigned}
+	}
+	idx := roomIndex(assigned.Label)
+	if standing < idx {
+		standing = idx
+	}
+
+	arena := ps.User.RoomLabel
+	if idx != 0 {
+		arena = ps.Stream.DefaultCommunity
+		if arena == "" {
+			arena = RoomLabelArena
+		}
+	}
+
+	rooms := make([]Room, standing+1)
+	rooms[idx] = assigned
+	for i := idx - 1; i >= 0; i-- {
+		rooms[i], _ = rooms[i+1].Prev()
+	}
+	for i := idx + 1; i <= standing; i++ {
+		rooms[i], _ = rooms[i-1].Next()
+	}
+	for i := range rooms {
+		rooms[i].Label = roomLabel(arena, i)
+	}
+	return rooms
+}
+
+// StreamingRoomsComment connects to rooms and returns the channel that receives
+// the comments of all rooms. Chats are sent in order of the posted time
+// without duplicates, and Room of Thread and Chat is set to the label of the room.
+// The channel is closed when all connections are closed.
+func (c *LiveClient) StreamingRoomsComment(ctx context.Context, rooms []Room, resFrom int64) (chan Comment, error) {
+	conns := make([]net.Conn, 0, len(rooms))
+	closeAll := func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}
+	for _, room := range rooms {
+		conn, err := c.dial(ctx, room.Addr, room.Port)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		conns = append(conns, conn)
+		if err := sendThread(conn, room.Thread, resFrom); err != nil {
+			closeAll()
+			return nil, err
+		}
+	}
+
+	in := make(chan Comment)
+	var wg sync.WaitGroup
+	for i, room := range rooms {
+		conn := conns[i]
+		wg.Add(1)
+		go func(room Room) {
+			defer wg.Done()
+			defer conn.Close()
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+
+			dec := NewCommentDecoder(conn)
+			for {
+				cmt, err := dec.Decode()
+				if err != nil {
+					if ctx.Err() == nil {
+						sendComment(ctx, in, &CommentError{fmt.Errorf("%s: %w", room.Label, err)})
+					}
+					return
+				}
+				switch v := cmt.(type) {
+				case *Thread:
+					v.Room = room.Label
+				case *Chat:
+					v.Room = room.Label
+				}
+				if !sendComment(ctx, in, cmt) {
+					return
+				}
+			}
+		}(room)
+	}
+	go func() {
+		wg.Wait()
+		close(in)
+	}()
+
+	ch := make(chan Comment)
+	go mergeComments(ctx, in, ch, mergeWindow)
+	return ch, nil
+}
+
+func sendComment(ctx context.Context, ch chan<- Comment, cmt Comment) bool {
+	select {
+	case ch <- cmt:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// mergeComments sends the comments from in to out.
+// Chats are held for window and sent in order of the posted time without duplicates.
+// A chat is duplicated if the user, the posted time, the mail and the comment are the same,
+// since a chat seen in several rooms has a different thread and number in each room.
+// The other comments are sent immediately. out is closed after in is closed.
+func mergeComments(ctx context.Context, in <-chan Comment, out chan<- Comment, window time.Duration) {
+	defer close(out)
+
+	var pending chatHeap
+	seen := newChatSet(10000)
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+
+	for in != nil || pending.Len() > 0 {
+		var next Comment
+		var send chan<- Comment
+		if pending.Len() > 0 && (in == nil || time.Since(pending[0].received) >= window) {
+			next, send = pending[0].chat, out
+		}
+
+		if pending.Len() > 0 && send == nil {
+			timer.Reset(window - time.Since(pending[0].received))
+		}
+
+		select {
+		case cmt, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			chat, isChat := cmt.(*Chat)
+			if !isChat {
+				if !sendComment(ctx, out, cmt) {
+					return
+				}
+				continue
+			}
+			if seen.add(contentKey(chat)) {
+				heap.Push(&pending, pendingChat{chat: chat, received: time.Now()})
+			}
+		case send <- next:
+			heap.Pop(&pending)
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// chatKey is the key of a chat in a thread.
+type chatKey struct {
+	thread int64
+	no     int64
+}
+
+// chatContentKey is the key of a chat among the rooms.
+// The same chat has a different thread and number in each room.
+type chatContentKey struct {
+	userID   string
+	date     int64
+	dateUsec int64
+	mail     string
+	comment  string
+}
+
+func contentKey(c *Chat) chatContentKey {
+	return chatContentKey{userID: c.UserID, date: c.Date, dateUsec: c.DateUsec, mail: c.Mail, comment: c.Comment}
+}
+
+// chatSet is a set of the keys of the chats, chatKey or chatContentKey.
+// It forgets the old keys when the number of the keys exceeds size.
+type chatSet struct {
+	size      int
+	cur, prev map[interface{}]struct{}
+}
+
+func newChatSet(size int) *chatSet {
+	return &chatSet{size: size, cur: map[interface{}]struct{}{}}
+}
+
+// add adds k and reports whether k is new.
+func (s *chatSet) add(k interface{}) bool {
+	if _, ok := s.cur[k]; ok {
+		return false
+	}
+	if _, ok := s.prev[k]; ok {
+		return false
+	}
+	if len(s.cur) >= s.size {
+		s.prev, s.cur = s.cur, map[interface{}]struct{}{}
+	}
+	s.cur[k] = struct{}{}
+	return true
+}
+
+type pendingChat struct {
+	chat     *Chat
+	received time.Time
+}
+
+// chatHeap is a heap of the chats ordered by the posted time.
+type chatHeap []pendingChat
+
+func (h chatHeap) Len() int { return len(h) }
+
+func (h chatHeap) Less(i, k int) bool {
+	a, b := h[i].chat, h[k].chat
+	if a.Date != b.Date {
+		return a.Date < b.Date
+	}
+	if a.DateUsec != b.DateUsec {
+		return a.DateUsec < b.DateUsec
+	}
+	if a.Thread != b.Thread {
+		return a.Thread < b.Thread
+	}
+	return a.No < b.No
+}
+
+func (h chatHeap) Swap(i, k int) { h[i], h[k] = h[k], h[i] }
+
+func (h *chatHeap) Push(x interface{}) { *h = append(*h, x.(pendingChat)) }
+
+func (h *chatHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,250 @@
+package nico
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRoom_NextPrev(t *testing.T) {
+	r := Room{Addr: "omsg104.live.nicovideo.jp", Port: 2814, Thread: 100}
+	want := Room{Addr: "omsg101.live.nicovideo.jp", Port: 2805, Thread: 101}
+	if got, ok := r.Next(); !ok || got != want {
+		t.Fatalf("want %+v but %+v", want, got)
+	}
+	if got, ok := want.Prev(); !ok || got != r {
+		t.Fatalf("want %+v but %+v", r, got)
+	}
+
+	r = Room{Addr: "omsg102.live.nicovideo.jp", Port: 2810, Thread: 100}
+	want = Room{Addr: "omsg102.live.nicovideo.jp", Port: 2811, Thread: 101}
+	if got, ok := r.Next(); !ok || got != want {
+		t.Fatalf("want %+v but %+v", want, got)
+	}
+
+	// The port of the fixture is out of the ports of the rooms.
+	b, err := ioutil.ReadFile("testdata/getplayerstatus.xml")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	var ps PlayerStatus
+	if err := xml.Unmarshal(b, &ps); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if _, ok := ps.Room().Next(); ok {
+		t.Fatalf("next room of port %d should not be derived", ps.Ms.Port)
+	}
+	if _, ok := ps.Room().Prev(); ok {
+		t.Fatalf("previous room of port %d should not be derived", ps.Ms.Port)
+	}
+	if got := ps.Rooms(3); !reflect.DeepEqual(got, []Room{ps.Room()}) {
+		t.Fatalf("want %+v but %+v", []Room{ps.Room()}, got)
+	}
+}
+
+func TestPlayerStatus_Rooms(t *testing.T) {
+	ps := &PlayerStatus{
+		Stream: Stream{DefaultCommunity: "co1234567"},
+		User:   User{RoomLabel: "立ち見A列"},
+		Ms:     Ms{Addr: "omsg101.live.nicovideo.jp", Port: 2805, Thread: 1000},
+	}
+	want := []Room{
+		{Label: "co1234567", Addr: "omsg104.live.nicovideo.jp", Port: 2814, Thread: 999},
+		{Label: "立ち見A列", Addr: "omsg101.live.nicovideo.jp", Port: 2805, Thread: 1000},
+		{Label: "立ち見B列", Addr: "omsg101.live.nicovideo.jp", Port: 2806, Thread: 1001},
+		{Label: "立ち見C列", Addr: "omsg101.live.nicovideo.jp", Port: 2807, Thread: 1002},
+	}
+	if got := ps.Rooms(3); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v but %+v", want, got)
+	}
+
+	// The assigned room is included even if it is over standing.
+	if got := ps.Rooms(0); !reflect.DeepEqual(got, want[:2]) {
+		t.Fatalf("want %+v but %+v", want[:2], got)
+	}
+
+	ps.User.RoomLabel = "co1234567"
+	if got := ps.Rooms(0); len(got) != 1 || got[0].Label != "co1234567" || got[0].Thread != 1000 {
+		t.Fatalf("want the arena only but %+v", got)
+	}
+}
+
+// newCommentServer starts the comment server that sends frames after the thread request.
+func newCommentServer(t *testing.T, frames ...interface{}) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if _, err := r.ReadBytes(0); err != nil {
+					return
+				}
+				for _, f := range frames {
+					b, err := xml.Marshal(f)
+					if err != nil {
+						return
+					}
+					if _, err := conn.Write(append(b, 0)); err != nil {
+						return
+					}
+				}
+				// Wait until the client closes.
+				r.ReadBytes(0)
+			}()
+		}
+	}()
+	return ln
+}
+
+// dialTo returns DialContextFunc that connects to the listener of the port instead.
+func dialTo(lns map[int64]net.Listener) DialContextFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		for p, ln := range lns {
+			if fmt.Sprint(p) == port {
+				var d net.Dialer
+				return d.DialContext(ctx, network, ln.Addr().String())
+			}
+		}
+		return nil, fmt.Errorf("unknown address: %s", addr)
+	}
+}
+
+func TestLiveClient_StreamingRoomsComment(t *testing.T) {
+	arena := newCommentServer(t,
+		Thread{Thread: 999, Resultcode: 0},
+		Chat{Thread: 999, No: 1, Date: 100, Comment: "arena1"},
+		Chat{Thread: 999, No: 2, Date: 102, Comment: "arena2"},
+		Chat{Thread: 999, No: 2, Date: 102, Comment: "arena2"},
+	)
+	defer arena.Close()
+	standing := newCommentServer(t,
+		Thread{Thread: 1000, Resultcode: 0},
+		Chat{Thread: 1000, No: 1, Date: 101, Comment: "standing1"},
+		Chat{Thread: 1000, No: 2, Date: 103, Comment: "standing2"},
+	)
+	defer standing.Close()
+
+	c := NewClient(WithDialContext(dialTo(map[int64]net.Listener{2814: arena, 2805: standing})))
+	ps := &PlayerStatus{
+		Stream: Stream{DefaultCommunity: "co1234567"},
+		User:   User{RoomLabel: "立ち見A列"},
+		Ms:     Ms{Addr: "omsg101.live.nicovideo.jp", Port: 2805, Thread: 1000},
+	}
+	lc := &LiveClient{Client: c, PlayerStatus: ps}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := lc.StreamingRoomsComment(ctx, ps.Rooms(1), 0)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	threads := map[string]bool{}
+	var chats []string
+	timeout := time.After(5 * time.Second)
+	for len(chats) < 4 {
+		select {
+		case cmt := <-ch:
+			switch v := cmt.(type) {
+			case *Thread:
+				threads[v.Room] = true
+			case *Chat:
+				chats = append(chats, v.Room+":"+v.Comment)
+			case *CommentError:
+				t.Fatalf("should not be fail: %v", v.error)
+			}
+		case <-timeout:
+			t.Fatalf("timed out: %v", chats)
+		}
+	}
+	if !threads["co1234567"] || !threads["立ち見A列"] {
+		t.Fatalf("want threads of all rooms but %v", threads)
+	}
+	want := []string{"co1234567:arena1", "立ち見A列:standing1", "co1234567:arena2", "立ち見A列:standing2"}
+	if !reflect.DeepEqual(chats, want) {
+		t.Fatalf("want %v but %v", want, chats)
+	}
+
+	// The duplicated chat is not sent and the channel is closed on cancel.
+	cancel()
+	for cmt := range ch {
+		if chat, ok := cmt.(*Chat); ok {
+			t.Fatalf("should not receive the duplicated chat: %+v", chat)
+		}
+	}
+}
+
+func TestLiveClient_StreamingRoomsCommentDuplicatedAcrossRooms(t *testing.T) {
+	arena := newCommentServer(t,
+		Chat{Thread: 999, No: 1, Date: 100, DateUsec: 5, UserID: "2525", Comment: "both"},
+		Chat{Thread: 999, No: 2, Date: 102, UserID: "2525", Comment: "arena"},
+	)
+	defer arena.Close()
+	standing := newCommentServer(t,
+		Chat{Thread: 1000, No: 7, Date: 100, DateUsec: 5, UserID: "2525", Comment: "both"},
+		Chat{Thread: 1000, No: 8, Date: 101, UserID: "2525", Comment: "both"},
+	)
+	defer standing.Close()
+
+	c := NewClient(WithDialContext(dialTo(map[int64]net.Listener{2814: arena, 2805: standing})))
+	ps := &PlayerStatus{
+		Stream: Stream{DefaultCommunity: "co1234567"},
+		User:   User{RoomLabel: "立ち見A列"},
+		Ms:     Ms{Addr: "omsg101.live.nicovideo.jp", Port: 2805, Thread: 1000},
+	}
+	lc := &LiveClient{Client: c, PlayerStatus: ps}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := lc.StreamingRoomsComment(ctx, ps.Rooms(1), 0)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	var chats []string
+	timeout := time.After(5 * time.Second)
+	for len(chats) < 3 {
+		select {
+		case cmt := <-ch:
+			switch v := cmt.(type) {
+			case *Chat:
+				chats = append(chats, fmt.Sprintf("%d:%s", v.Date, v.Comment))
+			case *CommentError:
+				t.Fatalf("should not be fail: %v", v.error)
+			}
+		case <-timeout:
+			t.Fatalf("timed out: %v", chats)
+		}
+	}
+	// The chat posted at another time is not a duplicate.
+	want := []string{"100:both", "101:both", "102:arena"}
+	if !reflect.DeepEqual(chats, want) {
+		t.Fatalf("want %v but %v", want, chats)
+	}
+
+	cancel()
+	for cmt := range ch {
+		if chat, ok := cmt.(*Chat); ok {
+			t.Fatalf("should not receive the duplicated chat: %+v", chat)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package rtmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// AMF0 type markers.
+const (
+	amf0Number      = 0x00
+	amf0Boolean     = 0x01
+	amf0String      = 0x02
+	amf0Object      = 0x03
+	amf0Null        = 0x05
+	amf0Undefined   = 0x06
+	amf0ECMAArray   = 0x08
+	amf0ObjectEnd   = 0x09
+	amf0StrictArray = 0x0a
+	amf0Date        = 0x0b
+	amf0LongString  = 0x0c
+)
+
+// Object is an AMF0 object. An ECMA array is also decoded to Object.
+type Object map[string]interface{}
+
+// EncodeAMF0 encodes vals in AMF0.
+// A value must be nil, bool, a number, string, Object, []interface{} or time.Time.
+func EncodeAMF0(vals ...interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, v := range vals {
+		if err := encodeAMF0(&buf, v); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeAMF0(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(amf0Null)
+	case bool:
+		buf.WriteByte(amf0Boolean)
+		if v {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case float64:
+		buf.WriteByte(amf0Number)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case int:
+		return encodeAMF0(buf, float64(v))
+	case int64:
+		return encodeAMF0(buf, float64(v))
+	case uint32:
+		return encodeAMF0(buf, float64(v))
+	case string:
+		if len(v) > math.MaxUint16 {
+			buf.WriteByte(amf0LongString)
+			binary.Write(buf, binary.BigEndian, uint32(len(v)))
+			buf.WriteString(v)
+			break
+		}
+		buf.WriteByte(amf0String)
+		writeAMF0Key(buf, v)
+	case Object:
+		buf.WriteByte(amf0Object)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writeAMF0Key(buf, k)
+			if err := encodeAMF0(buf, v[k]); err != nil {
+				return err
+			}
+		}
+		buf.Write([]byte{0, 0, amf0ObjectEnd})
+	case []interface{}:
+		buf.WriteByte(amf0StrictArray)
+		binary.Write(buf, binary.BigEndian, uint32(len(v)))
+		for _, e := range v {
+			if err := encodeAMF0(buf, e); err != nil {
+				return err
+			}
+		}
+	case time.Time:
+		buf.WriteByte(amf0Date)
+		ms := float64(v.UnixNano()) / float64(time.Millisecond)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(ms))
+		buf.Write([]byte{0, 0})
+	default:
+		return fmt.Errorf("amf0: unsupported type %T", v)
+	}
+	return nil
+}
+
+func writeAMF0Key(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.BigEndian, uint16(len(s)))
+	buf.WriteString(s)
+}
+
+// DecodeAMF0 decodes all values in b.
+// Numbers are decoded to float64 and null and undefined to nil.
+func DecodeAMF0(b []byte) ([]interface{}, error) {
+	r := bytes.NewReader(b)
+	var vals []interface{}
+	for r.Len() > 0 {
+		v, err := decodeAMF0(r)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+	}
+	return vals, nil
+}
+
+var errAMF0Truncated = errors.New("amf0: truncated data")
+
+func decodeAMF0(r *bytes.Reader) (interface{}, error) {
+	marker, err := r.ReadByte()
+	if err != nil {
+		return nil, errAMF0Truncated
+	}
+	switch marker {
+	case amf0Number:
+		var n uint64
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, errAMF0Truncated
+		}
+		return math.Float64frombits(n), nil
+	case amf0Boolean:
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, errAMF0Truncated
+		}
+		return b != 0, nil
+	case amf0String:
+		return readAMF0Key(r)
+	case amf0LongString:
+		var n uint32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, errAMF0Truncated
+		}
+		return readAMF0String(r, int(n))
+	case amf0Object:
+		return decodeAMF0Object(r)
+	case amf0ECMAArray:
+		// The count is only a hint. The properties end with the object end marker.
+		if _, err := r.Seek(4, io.SeekCurrent); err != nil || r.Len() == 0 {
+			return nil, errAMF0Truncated
+		}
+		return decodeAMF0Object(r)
+	case amf0StrictArray:
+		var n uint32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, errAMF0Truncated
+		}
+		if int64(n) > int64(r.Len()) {
+			return nil, errAMF0Truncated
+		}
+		arr := make([]interface{}, 0, n)
+		for i := uint32(0); i < n; i++ {
+			v, err := decodeAMF0(r)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case amf0Date:
+		var ms uint64
+		if err := binary.Read(r, binary.BigEndian, &ms); err != nil {
+			return nil, errAMF0Truncated
+		}
+		if _, err := r.Seek(2, io.SeekCurrent); err != nil {
+			return nil, errAMF0Truncated
+		}
+		return time.Unix(0, int64(math.Float64frombits(ms)*float64(time.Millisecond))), nil
+	case amf0Null, amf0Undefined:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("amf0: unsupported marker %#x", marker)
+}
+
+func decodeAMF0Object(r *bytes.Reader) (Object, error) {
+	obj := Object{}
+	for {
+		k, err := readAMF0Key(r)
+		if err != nil {
+			return nil, err
+		}
+		if k == "" {
+			m, err := r.ReadByte()
+			if err != nil {
+				return nil, errAMF0Truncated
+			}
+			if m == amf0ObjectEnd {
+				return obj, nil
+			}
+			if err := r.UnreadByte(); err != nil {
+				return nil, err
+			}
+		}
+		v, err := decodeAMF0(r)
+		if err != nil {
+			return nil, err
+		}
+		obj[k] = v
+	}
+}
+
+func readAMF0Key(r *bytes.Reader) (string, error) {
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return "", errAMF0Truncated
+	}
+	return readAMF0String(r, int(n))
+}
+
+func readAMF0String(r *bytes.Reader, n int) (string, error) {
+	if n > r.Len() {
+		return "", errAMF0Truncated
+	}
+	b := make([]byte, n)
+	io.ReadFull(r, b)
+	return string(b), nil
+}
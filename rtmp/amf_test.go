@@ -0,0 +1,71 @@
+package rtmp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAMF0(t *testing.T) {
+	date := time.Unix(1500000000, 0)
+	vals := []interface{}{
+		"connect",
+		1.0,
+		Object{"app": "live", "fpad": false, "capabilities": 15.0, "nested": Object{"a": nil}},
+		nil,
+		true,
+		[]interface{}{"a", 2.0},
+		date,
+		strings.Repeat("x", 70000),
+	}
+	b, err := EncodeAMF0(vals...)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	got, err := DecodeAMF0(b)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if len(got) != len(vals) {
+		t.Fatalf("want %d but %d", len(vals), len(got))
+	}
+	if gd, ok := got[6].(time.Time); !ok || !gd.Equal(date) {
+		t.Fatalf("want %v but %v", date, got[6])
+	}
+	got[6], vals[6] = nil, nil
+	if !reflect.DeepEqual(got, vals) {
+		t.Fatalf("want %v but %v", vals, got)
+	}
+
+	if _, err := EncodeAMF0(struct{}{}); err == nil {
+		t.Fatalf("should be fail")
+	}
+}
+
+func TestDecodeAMF0_ECMAArray(t *testing.T) {
+	b := []byte{
+		amf0ECMAArray, 0, 0, 0, 1,
+		0, 8, 'd', 'u', 'r', 'a', 't', 'i', 'o', 'n',
+		amf0Number, 0x40, 0x24, 0, 0, 0, 0, 0, 0,
+		0, 0, amf0ObjectEnd,
+		amf0Undefined,
+	}
+	got, err := DecodeAMF0(b)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	want := []interface{}{Object{"duration": 10.0}, nil}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v but %v", want, got)
+	}
+}
+
+func TestDecodeAMF0_Truncated(t *testing.T) {
+	b, _ := EncodeAMF0(Object{"app": "live", "fpad": false})
+	for i := 1; i < len(b); i++ {
+		if _, err := DecodeAMF0(b[:i]); err == nil {
+			t.Fatalf("should be fail: %v", b[:i])
+		}
+	}
+}
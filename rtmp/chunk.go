@@ -0,0 +1,256 @@
+package rtmp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	defaultChunkSize = 128
+	maxChunkSize     = 0xffffff
+	maxMessageSize   = 0xffffff
+	extendedTime     = 0xffffff
+)
+
+// Message is an RTMP message.
+type Message struct {
+	Type      uint8
+	StreamID  uint32
+	Timestamp uint32
+	Payload   []byte
+}
+
+// chunkStream is the state of a chunk stream to decode the compressed headers.
+type chunkStream struct {
+	timestamp uint32
+	delta     uint32
+	length    uint32
+	typ       uint8
+	streamID  uint32
+	extended  bool
+	started   bool
+	buf       []byte
+}
+
+// chunkReader reads the messages from the chunk streams.
+type chunkReader struct {
+	r         *bufio.Reader
+	chunkSize uint32
+	streams   map[uint32]*chunkStream
+	n         uint64
+}
+
+func newChunkReader(r io.Reader) *chunkReader {
+	cr := &chunkReader{chunkSize: defaultChunkSize, streams: map[uint32]*chunkStream{}}
+	cr.r = bufio.NewReader(countReader{r, &cr.n})
+	return cr
+}
+
+type countReader struct {
+	r io.Reader
+	n *uint64
+}
+
+func (r countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	*r.n += uint64(n)
+	return n, err
+}
+
+func (cr *chunkReader) readUint(n int) (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(cr.r, b[4-n:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b[:]), nil
+}
+
+// readMessage reads the chunks until a message is completed.
+func (cr *chunkReader) readMessage() (*Message, error) {
+	for {
+		m, err := cr.readChunk()
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			return m, nil
+		}
+	}
+}
+
+// readChunk reads a chunk and returns the message if it is completed.
+func (cr *chunkReader) readChunk() (*Message, error) {
+	b, err := cr.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	format := b >> 6
+	csid := uint32(b & 0x3f)
+	switch csid {
+	case 0:
+		v, err := cr.readUint(1)
+		if err != nil {
+			return nil, err
+		}
+		csid = 64 + v
+	case 1:
+		v, err := cr.readUint(2)
+		if err != nil {
+			return nil, err
+		}
+		csid = 64 + v>>8 + (v&0xff)<<8
+	}
+
+	cs, ok := cr.streams[csid]
+	if !ok {
+		if format != 0 {
+			return nil, fmt.Errorf("rtmp: chunk stream %d starts with format %d", csid, format)
+		}
+		cs = &chunkStream{}
+		cr.streams[csid] = cs
+	}
+
+	var ts uint32
+	if format <= 2 {
+		if ts, err = cr.readUint(3); err != nil {
+			return nil, err
+		}
+	}
+	if format <= 1 {
+		length, err := cr.readUint(3)
+		if err != nil {
+			return nil, err
+		}
+		if len(cs.buf) > 0 && length != cs.length {
+			return nil, errors.New("rtmp: message length is changed in the middle of the message")
+		}
+		cs.length = length
+		t, err := cr.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		cs.typ = t
+	}
+	if format == 0 {
+		var b [4]byte
+		if _, err := io.ReadFull(cr.r, b[:]); err != nil {
+			return nil, err
+		}
+		cs.streamID = binary.LittleEndian.Uint32(b[:])
+	}
+	if format <= 2 {
+		cs.extended = ts == extendedTime
+	}
+	if cs.extended {
+		if ts, err = cr.readUint(4); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cs.buf) == 0 {
+		// The first chunk of a message.
+		switch format {
+		case 0:
+			cs.timestamp = ts
+			cs.delta = 0
+		case 1, 2:
+			cs.delta = ts
+			cs.timestamp += ts
+		case 3:
+			if cs.started {
+				cs.timestamp += cs.delta
+			}
+		}
+		cs.started = true
+		if cs.length > maxMessageSize {
+			return nil, errors.New("rtmp: message is too large")
+		}
+		cs.buf = make([]byte, 0, cs.length)
+	}
+
+	n := cs.length - uint32(len(cs.buf))
+	if n > cr.chunkSize {
+		n = cr.chunkSize
+	}
+	start := len(cs.buf)
+	cs.buf = cs.buf[:start+int(n)]
+	if _, err := io.ReadFull(cr.r, cs.buf[start:]); err != nil {
+		return nil, err
+	}
+	if uint32(len(cs.buf)) < cs.length {
+		return nil, nil
+	}
+
+	m := &Message{Type: cs.typ, StreamID: cs.streamID, Timestamp: cs.timestamp, Payload: cs.buf}
+	cs.buf = nil
+	return m, nil
+}
+
+// chunkWriter writes the messages as chunks.
+// Every message is written with the full header for simplicity.
+type chunkWriter struct {
+	w         *bufio.Writer
+	chunkSize uint32
+}
+
+func newChunkWriter(w io.Writer) *chunkWriter {
+	return &chunkWriter{w: bufio.NewWriter(w), chunkSize: defaultChunkSize}
+}
+
+func (cw *chunkWriter) writeMessage(csid uint32, m *Message) error {
+	if len(m.Payload) > maxMessageSize {
+		return errors.New("rtmp: message is too large")
+	}
+	ts := m.Timestamp
+	if ts >= extendedTime {
+		ts = extendedTime
+	}
+
+	cw.writeBasicHeader(0, csid)
+	cw.w.Write([]byte{byte(ts >> 16), byte(ts >> 8), byte(ts)})
+	l := len(m.Payload)
+	cw.w.Write([]byte{byte(l >> 16), byte(l >> 8), byte(l), m.Type})
+	var sid [4]byte
+	binary.LittleEndian.PutUint32(sid[:], m.StreamID)
+	cw.w.Write(sid[:])
+	cw.writeExtendedTime(m.Timestamp)
+
+	p := m.Payload
+	for {
+		n := len(p)
+		if n > int(cw.chunkSize) {
+			n = int(cw.chunkSize)
+		}
+		cw.w.Write(p[:n])
+		p = p[n:]
+		if len(p) == 0 {
+			break
+		}
+		cw.writeBasicHeader(3, csid)
+		cw.writeExtendedTime(m.Timestamp)
+	}
+	return cw.w.Flush()
+}
+
+func (cw *chunkWriter) writeBasicHeader(format uint8, csid uint32) {
+	switch {
+	case csid < 64:
+		cw.w.WriteByte(format<<6 | byte(csid))
+	case csid < 64+256:
+		cw.w.Write([]byte{format << 6, byte(csid - 64)})
+	default:
+		v := csid - 64
+		cw.w.Write([]byte{format<<6 | 1, byte(v), byte(v >> 8)})
+	}
+}
+
+func (cw *chunkWriter) writeExtendedTime(ts uint32) {
+	if ts >= extendedTime {
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], ts)
+		cw.w.Write(b[:])
+	}
+}
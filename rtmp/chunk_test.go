@@ -0,0 +1,89 @@
+package rtmp
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestChunkReader_CompressedHeaders(t *testing.T) {
+	b := []byte{
+		// Format 0 on chunk stream 4: timestamp 1000, length 3, audio, stream 1.
+		0x04, 0x00, 0x03, 0xe8, 0x00, 0x00, 0x03, TypeAudio, 0x01, 0x00, 0x00, 0x00, 'a', 'b', 'c',
+		// Format 1: delta 20, length 2, video.
+		0x44, 0x00, 0x00, 0x14, 0x00, 0x00, 0x02, TypeVideo, 'd', 'e',
+		// Format 2: delta 30.
+		0x84, 0x00, 0x00, 0x1e, 'f', 'g',
+		// Format 3: same delta.
+		0xc4, 'h', 'i',
+		// Format 0 with the 2 bytes basic header on chunk stream 64+10.
+		0x00, 10, 0x00, 0x00, 0x05, 0x00, 0x00, 0x01, TypeAudio, 0x01, 0x00, 0x00, 0x00, 'j',
+	}
+	cr := newChunkReader(bytes.NewReader(b))
+	want := []Message{
+		{Type: TypeAudio, StreamID: 1, Timestamp: 1000, Payload: []byte("abc")},
+		{Type: TypeVideo, StreamID: 1, Timestamp: 1020, Payload: []byte("de")},
+		{Type: TypeVideo, StreamID: 1, Timestamp: 1050, Payload: []byte("fg")},
+		{Type: TypeVideo, StreamID: 1, Timestamp: 1080, Payload: []byte("hi")},
+		{Type: TypeAudio, StreamID: 1, Timestamp: 5, Payload: []byte("j")},
+	}
+	for _, w := range want {
+		m, err := cr.readMessage()
+		if err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+		if !reflect.DeepEqual(*m, w) {
+			t.Fatalf("want %+v but %+v", w, *m)
+		}
+	}
+}
+
+func TestChunkWriter_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	cw := newChunkWriter(&buf)
+	cw.chunkSize = 10
+
+	msgs := []*Message{
+		{Type: TypeVideo, StreamID: 1, Timestamp: 0x1000000, Payload: bytes.Repeat([]byte("v"), 25)},
+		{Type: TypeAudio, StreamID: 1, Timestamp: 40, Payload: bytes.Repeat([]byte("a"), 10)},
+		{Type: TypeAudio, StreamID: 1, Timestamp: 60, Payload: []byte{}},
+	}
+	for _, m := range msgs {
+		if err := cw.writeMessage(400, m); err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+	}
+
+	cr := newChunkReader(&buf)
+	cr.chunkSize = 10
+	for _, want := range msgs {
+		m, err := cr.readMessage()
+		if err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+		if m.Type != want.Type || m.Timestamp != want.Timestamp || !bytes.Equal(m.Payload, want.Payload) {
+			t.Fatalf("want %+v but %+v", want, m)
+		}
+	}
+}
+
+func TestChunkReader_LengthChanged(t *testing.T) {
+	b := []byte{
+		// Format 0 on chunk stream 4: length 200, video.
+		0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc8, TypeVideo, 0x01, 0x00, 0x00, 0x00,
+	}
+	b = append(b, bytes.Repeat([]byte("v"), defaultChunkSize)...)
+	// Format 1 with length 300 in the middle of the message.
+	b = append(b, 0x44, 0x00, 0x00, 0x00, 0x00, 0x01, 0x2c, TypeVideo)
+	b = append(b, bytes.Repeat([]byte("v"), 200-defaultChunkSize)...)
+
+	cr := newChunkReader(bytes.NewReader(b))
+	var err error
+	for err == nil {
+		_, err = cr.readMessage()
+	}
+	if err == io.EOF {
+		t.Fatalf("should be fail: %v", err)
+	}
+}
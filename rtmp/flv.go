@@ -0,0 +1,139 @@
+package rtmp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// FLV tag types. They are the same as the message types.
+const (
+	flvTagAudio  = TypeAudio
+	flvTagVideo  = TypeVideo
+	flvTagScript = TypeDataAMF0
+)
+
+const flvTagHeaderSize = 11
+
+// FLVWriter writes the audio, video and data messages as FLV tags.
+// The timestamps are relative to the first message.
+type FLVWriter struct {
+	w       io.Writer
+	started bool
+	base    uint32
+}
+
+// NewFLVWriter returns new FLVWriter that writes to w.
+func NewFLVWriter(w io.Writer) *FLVWriter {
+	return &FLVWriter{w: w}
+}
+
+func (fw *FLVWriter) writeHeader() error {
+	// Signature, version, flags of audio and video, header size and PreviousTagSize0.
+	_, err := fw.w.Write([]byte{'F', 'L', 'V', 1, 0x05, 0, 0, 0, 9, 0, 0, 0, 0})
+	return err
+}
+
+// WriteMessage writes m as FLV tags. Other messages than audio, video, data
+// and aggregate are ignored.
+func (fw *FLVWriter) WriteMessage(m *Message) error {
+	switch m.Type {
+	case TypeAudio, TypeVideo:
+		return fw.writeTag(m.Type, m.Timestamp, m.Payload)
+	case TypeDataAMF0:
+		return fw.writeTag(flvTagScript, m.Timestamp, trimSetDataFrame(m.Payload))
+	case TypeAggregate:
+		return fw.writeAggregate(m)
+	}
+	return nil
+}
+
+func (fw *FLVWriter) writeTag(typ uint8, ts uint32, data []byte) error {
+	if !fw.started {
+		if err := fw.writeHeader(); err != nil {
+			return err
+		}
+		fw.started = true
+		fw.base = ts
+	}
+	if ts < fw.base {
+		ts = fw.base
+	}
+	ts -= fw.base
+
+	size := len(data)
+	h := []byte{
+		typ,
+		byte(size >> 16), byte(size >> 8), byte(size),
+		byte(ts >> 16), byte(ts >> 8), byte(ts), byte(ts >> 24),
+		0, 0, 0,
+	}
+	if _, err := fw.w.Write(h); err != nil {
+		return err
+	}
+	if _, err := fw.w.Write(data); err != nil {
+		return err
+	}
+	var prev [4]byte
+	binary.BigEndian.PutUint32(prev[:], uint32(flvTagHeaderSize+size))
+	_, err := fw.w.Write(prev[:])
+	return err
+}
+
+// writeAggregate writes the FLV tags in the aggregate message.
+// The timestamps of the tags are shifted to start from the timestamp of m.
+func (fw *FLVWriter) writeAggregate(m *Message) error {
+	p := m.Payload
+	var first uint32
+	for i := 0; len(p) > 0; i++ {
+		if len(p) < flvTagHeaderSize {
+			return errors.New("rtmp: truncated aggregate message")
+		}
+		typ := p[0]
+		size := int(p[1])<<16 | int(p[2])<<8 | int(p[3])
+		ts := uint32(p[7])<<24 | uint32(p[4])<<16 | uint32(p[5])<<8 | uint32(p[6])
+		if len(p) < flvTagHeaderSize+size+4 {
+			return errors.New("rtmp: truncated aggregate message")
+		}
+		if i == 0 {
+			first = ts
+		}
+		data := p[flvTagHeaderSize : flvTagHeaderSize+size]
+		if typ == flvTagScript {
+			data = trimSetDataFrame(data)
+		}
+		if err := fw.writeTag(typ, m.Timestamp+ts-first, data); err != nil {
+			return err
+		}
+		p = p[flvTagHeaderSize+size+4:]
+	}
+	return nil
+}
+
+// trimSetDataFrame removes "@setDataFrame" at the head of the data message.
+func trimSetDataFrame(b []byte) []byte {
+	prefix, _ := EncodeAMF0("@setDataFrame")
+	return bytes.TrimPrefix(b, prefix)
+}
+
+// Record reads the messages of the playing stream and writes them to w in FLV
+// until the stream is ended or ctx is done. It returns nil when the stream is ended.
+func (c *Conn) Record(ctx context.Context, w io.Writer) error {
+	stop := context.AfterFunc(ctx, func() { c.conn.Close() })
+	defer stop()
+
+	fw := NewFLVWriter(w)
+	for {
+		m, err := c.ReadMessage()
+		if err == ErrStreamEnd {
+			return nil
+		} else if err != nil {
+			return ctxErr(ctx, err)
+		}
+		if err := fw.WriteMessage(m); err != nil {
+			return err
+		}
+	}
+}
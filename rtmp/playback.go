@@ -0,0 +1,42 @@
+package rtmp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/178inaba/nico"
+)
+
+// DialPlayback connects to the RTMP server of pb with the ticket
+// and plays the stream of the source of pb.
+// The URL of the source is sent by nlPlayNotice before play
+// so that the edge server relays the stream from the origin.
+func DialPlayback(ctx context.Context, pb *nico.Playback, opts ...Option) (*Conn, error) {
+	if pb.Source.Stream == "" {
+		return nil, errors.New("rtmp: no stream name in the contents")
+	}
+	opts = append([]Option{WithConnectArgs(pb.Ticket)}, opts...)
+	c, err := Dial(ctx, pb.URL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { c.conn.SetDeadline(time.Now()) })
+	defer stop()
+	if pb.Source.URL != "" {
+		// nlPlayNotice has no result.
+		if err := c.writeCommand(0, "nlPlayNotice", 0.0, nil, pb.Source.URL, pb.Source.Stream, 0.0); err != nil {
+			c.Close()
+			return nil, ctxErr(ctx, err)
+		}
+	}
+	if err := c.Play(pb.Source.Stream); err != nil {
+		c.Close()
+		return nil, ctxErr(ctx, err)
+	}
+	if !stop() {
+		c.Close()
+		return nil, ctx.Err()
+	}
+	return c, nil
+}
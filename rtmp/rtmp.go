@@ -0,0 +1,470 @@
+// Package rtmp provides a minimal RTMP client to play a live stream
+// of niconico live and record it to FLV.
+package rtmp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPort is the default port of RTMP.
+const DefaultPort = "1935"
+
+// Message types.
+const (
+	TypeSetChunkSize     = 1
+	TypeAbort            = 2
+	TypeAck              = 3
+	TypeUserControl      = 4
+	TypeWindowAckSize    = 5
+	TypeSetPeerBandwidth = 6
+	TypeAudio            = 8
+	TypeVideo            = 9
+	TypeDataAMF0         = 18
+	TypeCommandAMF0      = 20
+	TypeAggregate        = 22
+)
+
+// Chunk stream IDs used by Conn.
+const (
+	csidControl = 2
+	csidCommand = 3
+)
+
+// User control event types.
+const (
+	eventSetBuffer   = 3
+	eventPingRequest = 6
+	eventPingReply   = 7
+)
+
+const (
+	handshakeSize     = 1536
+	rtmpVersion       = 3
+	defaultWindowSize = 2500000
+	defaultFlashVer   = "LNX 9,0,124,2"
+	clientChunkSize   = 4096
+	defaultBufferTime = 3000
+)
+
+// ErrStreamEnd is returned by ReadMessage when the stream is ended.
+var ErrStreamEnd = errors.New("rtmp: stream end")
+
+// StatusError is an error of onStatus or _error of a command.
+type StatusError struct {
+	Code        string
+	Description string
+}
+
+func (e *StatusError) Error() string {
+	if e.Description == "" {
+		return "rtmp: " + e.Code
+	}
+	return fmt.Sprintf("rtmp: %s: %s", e.Code, e.Description)
+}
+
+// Option is a function that configures a Conn.
+type Option func(*Conn)
+
+// WithApp sets the application name to connect.
+// It is the path of the URL by default.
+func WithApp(app string) Option {
+	return func(c *Conn) { c.app = app }
+}
+
+// WithConnectArgs sets the additional arguments of the connect command
+// such as the ticket of niconico live.
+func WithConnectArgs(args ...interface{}) Option {
+	return func(c *Conn) { c.connectArgs = args }
+}
+
+// WithFlashVer sets flashVer of the connect command.
+func WithFlashVer(v string) Option {
+	return func(c *Conn) { c.flashVer = v }
+}
+
+// WithDialer sets the dialer to connect to the server.
+func WithDialer(d *net.Dialer) Option {
+	return func(c *Conn) { c.dialer = d }
+}
+
+// Conn is a connection to an RTMP server.
+// ReadMessage and the commands must not be called concurrently.
+type Conn struct {
+	conn        net.Conn
+	dialer      *net.Dialer
+	app         string
+	tcURL       string
+	flashVer    string
+	connectArgs []interface{}
+
+	r  *chunkReader
+	wm sync.Mutex
+	w  *chunkWriter
+
+	windowSize uint32
+	acked      uint64
+	txn        float64
+	streamID   uint32
+	queue      []*Message
+}
+
+// Dial connects to the RTMP server of rawurl and sends the connect command.
+func Dial(ctx context.Context, rawurl string, opts ...Option) (*Conn, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "rtmp" {
+		return nil, fmt.Errorf("rtmp: unsupported scheme: %s", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), DefaultPort)
+	}
+
+	c := &Conn{
+		dialer:     &net.Dialer{},
+		app:        strings.TrimPrefix(u.Path, "/"),
+		tcURL:      rawurl,
+		flashVer:   defaultFlashVer,
+		windowSize: defaultWindowSize,
+	}
+	if u.RawQuery != "" {
+		c.app += "?" + u.RawQuery
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	conn, err := c.dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	c.r = newChunkReader(conn)
+	c.w = newChunkWriter(conn)
+
+	// Interrupt the handshake and connect if ctx is done.
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+	if err := c.handshake(); err != nil {
+		conn.Close()
+		return nil, ctxErr(ctx, err)
+	}
+	if err := c.connect(); err != nil {
+		conn.Close()
+		return nil, ctxErr(ctx, err)
+	}
+	if !stop() {
+		conn.Close()
+		return nil, ctx.Err()
+	}
+	return c, nil
+}
+
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// handshake does the simple handshake of RTMP.
+func (c *Conn) handshake() error {
+	c0c1 := make([]byte, 1+handshakeSize)
+	c0c1[0] = rtmpVersion
+	if _, err := rand.Read(c0c1[9:]); err != nil {
+		return err
+	}
+	if _, err := c.conn.Write(c0c1); err != nil {
+		return err
+	}
+
+	s0s1 := make([]byte, 1+handshakeSize)
+	if _, err := io.ReadFull(c.r.r, s0s1); err != nil {
+		return err
+	}
+	if s0s1[0] != rtmpVersion {
+		return fmt.Errorf("rtmp: unsupported version: %d", s0s1[0])
+	}
+
+	// C2 echoes S1.
+	c2 := s0s1[1:]
+	binary.BigEndian.PutUint32(c2[4:8], uint32(time.Now().Unix()))
+	if _, err := c.conn.Write(c2); err != nil {
+		return err
+	}
+
+	s2 := make([]byte, handshakeSize)
+	_, err := io.ReadFull(c.r.r, s2)
+	return err
+}
+
+func (c *Conn) connect() error {
+	if err := c.writeControl(TypeSetChunkSize, uint32(clientChunkSize)); err != nil {
+		return err
+	}
+	c.w.chunkSize = clientChunkSize
+
+	obj := Object{
+		"app":           c.app,
+		"flashVer":      c.flashVer,
+		"tcUrl":         c.tcURL,
+		"fpad":          false,
+		"capabilities":  15.0,
+		"audioCodecs":   3191.0,
+		"videoCodecs":   252.0,
+		"videoFunction": 1.0,
+	}
+	_, err := c.Call("connect", append([]interface{}{obj}, c.connectArgs...)...)
+	return err
+}
+
+// Call calls the command of name on the server and returns the arguments of the result.
+// It must be called before Play.
+func (c *Conn) Call(name string, args ...interface{}) ([]interface{}, error) {
+	c.txn++
+	txn := c.txn
+	vals := []interface{}{name, txn}
+	if name != "connect" {
+		// The command object of the commands except connect is null.
+		vals = append(vals, nil)
+	}
+	if err := c.writeCommand(0, append(vals, args...)...); err != nil {
+		return nil, err
+	}
+
+	for {
+		m, err := c.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if m.Type != TypeCommandAMF0 {
+			c.queue = append(c.queue, m)
+			continue
+		}
+		vals, err := DecodeAMF0(m.Payload)
+		if err != nil {
+			return nil, err
+		}
+		if len(vals) < 2 || vals[1] != txn {
+			continue
+		}
+		switch vals[0] {
+		case "_result":
+			return vals[2:], nil
+		case "_error":
+			return nil, statusError(vals[2:])
+		}
+	}
+}
+
+// statusError returns StatusError from the info object in vals.
+func statusError(vals []interface{}) error {
+	for _, v := range vals {
+		if info, ok := v.(Object); ok && info["code"] != nil {
+			code, _ := info["code"].(string)
+			desc, _ := info["description"].(string)
+			return &StatusError{Code: code, Description: desc}
+		}
+	}
+	return &StatusError{Code: "unknown"}
+}
+
+// Play creates a stream and plays the stream of name.
+// It returns after NetStream.Play.Start is received.
+func (c *Conn) Play(name string) error {
+	res, err := c.Call("createStream")
+	if err != nil {
+		return err
+	}
+	id, ok := lastNumber(res)
+	if !ok {
+		return errors.New("rtmp: no stream id in the result of createStream")
+	}
+	c.streamID = uint32(id)
+
+	if err := c.writeCommand(c.streamID, "play", 0.0, nil, name, -2.0); err != nil {
+		return err
+	}
+	if err := c.setBufferLength(defaultBufferTime); err != nil {
+		return err
+	}
+
+	for {
+		m, err := c.readMessage()
+		if err != nil {
+			return err
+		}
+		if m.Type != TypeCommandAMF0 {
+			c.queue = append(c.queue, m)
+			continue
+		}
+		code, err := onStatusCode(m)
+		if err != nil {
+			return err
+		}
+		if code == "NetStream.Play.Start" {
+			return nil
+		}
+	}
+}
+
+func lastNumber(vals []interface{}) (float64, bool) {
+	for i := len(vals) - 1; i >= 0; i-- {
+		if n, ok := vals[i].(float64); ok {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// onStatusCode returns the code of onStatus in m.
+// It returns StatusError if the level of the status is error.
+func onStatusCode(m *Message) (string, error) {
+	vals, err := DecodeAMF0(m.Payload)
+	if err != nil {
+		return "", err
+	}
+	if len(vals) == 0 || vals[0] != "onStatus" {
+		return "", nil
+	}
+	for _, v := range vals {
+		info, ok := v.(Object)
+		if !ok {
+			continue
+		}
+		code, _ := info["code"].(string)
+		if info["level"] == "error" {
+			desc, _ := info["description"].(string)
+			return code, &StatusError{Code: code, Description: desc}
+		}
+		return code, nil
+	}
+	return "", nil
+}
+
+// ReadMessage reads the next audio, video or data message of the playing stream.
+// It returns ErrStreamEnd when the stream is ended.
+func (c *Conn) ReadMessage() (*Message, error) {
+	for {
+		var m *Message
+		if len(c.queue) > 0 {
+			m, c.queue = c.queue[0], c.queue[1:]
+		} else {
+			var err error
+			if m, err = c.readMessage(); err != nil {
+				return nil, err
+			}
+		}
+
+		switch m.Type {
+		case TypeAudio, TypeVideo, TypeDataAMF0, TypeAggregate:
+			return m, nil
+		case TypeCommandAMF0:
+			code, err := onStatusCode(m)
+			if err != nil {
+				return nil, err
+			}
+			switch code {
+			case "NetStream.Play.Stop", "NetStream.Play.UnpublishNotify", "NetStream.Play.Complete":
+				return nil, ErrStreamEnd
+			}
+		}
+	}
+}
+
+// readMessage reads a message and handles the protocol control messages.
+func (c *Conn) readMessage() (*Message, error) {
+	for {
+		m, err := c.r.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.ack(); err != nil {
+			return nil, err
+		}
+
+		switch m.Type {
+		case TypeSetChunkSize:
+			if len(m.Payload) < 4 {
+				return nil, errors.New("rtmp: invalid set chunk size")
+			}
+			size := binary.BigEndian.Uint32(m.Payload) & 0x7fffffff
+			if size == 0 || size > maxChunkSize {
+				return nil, fmt.Errorf("rtmp: invalid chunk size: %d", size)
+			}
+			c.r.chunkSize = size
+		case TypeWindowAckSize:
+			if len(m.Payload) >= 4 {
+				c.windowSize = binary.BigEndian.Uint32(m.Payload)
+			}
+		case TypeSetPeerBandwidth:
+			if err := c.writeControl(TypeWindowAckSize, defaultWindowSize); err != nil {
+				return nil, err
+			}
+		case TypeUserControl:
+			if len(m.Payload) >= 6 && binary.BigEndian.Uint16(m.Payload) == eventPingRequest {
+				reply := append([]byte{0, eventPingReply}, m.Payload[2:6]...)
+				if err := c.writeMessage(csidControl, &Message{Type: TypeUserControl, Payload: reply}); err != nil {
+					return nil, err
+				}
+			}
+		case TypeAbort, TypeAck:
+		default:
+			return m, nil
+		}
+	}
+}
+
+// ack sends the acknowledgement if the received bytes exceed the window.
+func (c *Conn) ack() error {
+	if c.windowSize == 0 || c.r.n-c.acked < uint64(c.windowSize) {
+		return nil
+	}
+	c.acked = c.r.n
+	return c.writeControl(TypeAck, uint32(c.r.n))
+}
+
+func (c *Conn) setBufferLength(ms uint32) error {
+	b := make([]byte, 10)
+	binary.BigEndian.PutUint16(b, eventSetBuffer)
+	binary.BigEndian.PutUint32(b[2:], c.streamID)
+	binary.BigEndian.PutUint32(b[6:], ms)
+	return c.writeMessage(csidControl, &Message{Type: TypeUserControl, Payload: b})
+}
+
+func (c *Conn) writeControl(typ uint8, v uint32) error {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return c.writeMessage(csidControl, &Message{Type: typ, Payload: b})
+}
+
+func (c *Conn) writeCommand(streamID uint32, vals ...interface{}) error {
+	b, err := EncodeAMF0(vals...)
+	if err != nil {
+		return err
+	}
+	return c.writeMessage(csidCommand, &Message{Type: TypeCommandAMF0, StreamID: streamID, Payload: b})
+}
+
+func (c *Conn) writeMessage(csid uint32, m *Message) error {
+	c.wm.Lock()
+	defer c.wm.Unlock()
+	return c.w.writeMessage(csid, m)
+}
@@ -0,0 +1,183 @@
+package rtmp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/178inaba/nico"
+)
+
+const (
+	testTicket = "2525:lv301234567:0:1500000000:0123456789abcdef"
+	testStream = "lv301234567?1500000000:30:0123456789abcdef"
+)
+
+func testMedia() []*Message {
+	meta, _ := EncodeAMF0("@setDataFrame", "onMetaData", Object{"duration": 0.0})
+	// An aggregate message of a video tag at 0 and an audio tag at 20.
+	var agg []byte
+	for _, tag := range []struct {
+		typ  uint8
+		ts   uint32
+		data string
+	}{{TypeVideo, 500, "v2"}, {TypeAudio, 520, "a2"}} {
+		agg = append(agg, tag.typ, 0, 0, byte(len(tag.data)), byte(tag.ts>>16), byte(tag.ts>>8), byte(tag.ts), 0, 0, 0, 0)
+		agg = append(agg, tag.data...)
+		agg = binary.BigEndian.AppendUint32(agg, uint32(11+len(tag.data)))
+	}
+	return []*Message{
+		{Type: TypeDataAMF0, StreamID: 1, Timestamp: 1000, Payload: meta},
+		{Type: TypeVideo, StreamID: 1, Timestamp: 1000, Payload: bytes.Repeat([]byte("v"), 100)},
+		{Type: TypeAudio, StreamID: 1, Timestamp: 1023, Payload: []byte("a1")},
+		{Type: TypeAggregate, StreamID: 1, Timestamp: 1040, Payload: agg},
+	}
+}
+
+type flvTag struct {
+	typ  uint8
+	ts   uint32
+	data string
+}
+
+func parseFLV(t *testing.T, b []byte) []flvTag {
+	t.Helper()
+	if len(b) < 13 || string(b[:3]) != "FLV" {
+		t.Fatalf("invalid FLV header: %v", b)
+	}
+	b = b[13:]
+	var tags []flvTag
+	for len(b) > 0 {
+		size := int(b[1])<<16 | int(b[2])<<8 | int(b[3])
+		ts := uint32(b[7])<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6])
+		if prev := binary.BigEndian.Uint32(b[11+size:]); prev != uint32(11+size) {
+			t.Fatalf("want %d but %d", 11+size, prev)
+		}
+		tags = append(tags, flvTag{b[0], ts, string(b[11 : 11+size])})
+		b = b[11+size+4:]
+	}
+	return tags
+}
+
+func TestConn_Record(t *testing.T) {
+	s := newTestServer(t, testTicket, testStream, testMedia())
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := Dial(ctx, s.URL(), WithConnectArgs(testTicket))
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	defer c.Close()
+
+	args := <-s.connectArgs
+	obj, ok := args[0].(Object)
+	if !ok || obj["app"] != "liveedge/live_170714_00_0" || obj["tcUrl"] != s.URL() {
+		t.Fatalf("unexpected connect command: %v", args)
+	}
+
+	if err := c.Play(testStream); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := c.Record(ctx, &buf); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	meta, _ := EncodeAMF0("onMetaData", Object{"duration": 0.0})
+	want := []flvTag{
+		{TypeDataAMF0, 0, string(meta)},
+		{TypeVideo, 0, string(bytes.Repeat([]byte("v"), 100))},
+		{TypeAudio, 23, "a1"},
+		{TypeVideo, 40, "v2"},
+		{TypeAudio, 60, "a2"},
+	}
+	if got := parseFLV(t, buf.Bytes()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v but %v", want, got)
+	}
+}
+
+func TestDial_InvalidTicket(t *testing.T) {
+	s := newTestServer(t, testTicket, testStream, nil)
+	defer s.Close()
+
+	_, err := Dial(context.Background(), s.URL(), WithConnectArgs("invalid"))
+	var se *StatusError
+	if !errors.As(err, &se) || se.Code != "NetConnection.Connect.Rejected" {
+		t.Fatalf("want %s but %v", "NetConnection.Connect.Rejected", err)
+	}
+
+	if _, err := Dial(context.Background(), "http://example.com/"); err == nil {
+		t.Fatalf("should be fail")
+	}
+}
+
+func TestDialPlayback(t *testing.T) {
+	s := newTestServer(t, testTicket, testStream, testMedia())
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pb := &nico.Playback{URL: s.URL(), Ticket: testTicket, Source: nico.ContentSource{Stream: "unknown"}}
+	_, err := DialPlayback(ctx, pb)
+	var se *StatusError
+	if !errors.As(err, &se) || se.Code != "NetStream.Play.StreamNotFound" {
+		t.Fatalf("want %s but %v", "NetStream.Play.StreamNotFound", err)
+	}
+
+	pb.Source.URL = "rtmp://nlpoca123.live.nicovideo.jp:1935/publicorigin/170714_00_0/"
+	pb.Source.Stream = testStream
+	c, err := DialPlayback(ctx, pb)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	defer c.Close()
+	if args := <-s.connectArgs; len(args) < 2 || args[1] != testTicket {
+		t.Fatalf("want ticket %q but %v", testTicket, args)
+	}
+	select {
+	case args := <-s.playNotices:
+		if len(args) < 2 || args[0] != pb.Source.URL || args[1] != testStream {
+			t.Fatalf("want %q and %q but %v", pb.Source.URL, testStream, args)
+		}
+	default:
+		t.Fatalf("nlPlayNotice should be sent")
+	}
+
+	var n int
+	for {
+		_, err := c.ReadMessage()
+		if err == ErrStreamEnd {
+			break
+		} else if err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+		n++
+	}
+	if n != len(testMedia()) {
+		t.Fatalf("want %d but %d", len(testMedia()), n)
+	}
+}
+
+func TestConn_RecordCancel(t *testing.T) {
+	s := newTestServer(t, testTicket, testStream, nil)
+	defer s.Close()
+
+	c, err := Dial(context.Background(), s.URL(), WithConnectArgs(testTicket))
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Record(ctx, &bytes.Buffer{}); err != context.Canceled {
+		t.Fatalf("want %v but %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,145 @@
+package rtmp
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+// testServer is a stand-in RTMP server that plays a fixed stream.
+type testServer struct {
+	ln     net.Listener
+	ticket string
+	stream string
+	media  []*Message
+
+	// connectArgs is the arguments of the connect command received last.
+	connectArgs chan []interface{}
+
+	// playNotices is the arguments of nlPlayNotice.
+	playNotices chan []interface{}
+}
+
+func newTestServer(t *testing.T, ticket, stream string, media []*Message) *testServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	s := &testServer{ln: ln, ticket: ticket, stream: stream, media: media, connectArgs: make(chan []interface{}, 10), playNotices: make(chan []interface{}, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) URL() string {
+	return "rtmp://" + s.ln.Addr().String() + "/liveedge/live_170714_00_0"
+}
+
+func (s *testServer) Close() error {
+	return s.ln.Close()
+}
+
+func (s *testServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := newChunkReader(conn)
+	w := newChunkWriter(conn)
+
+	// Handshake.
+	c0c1 := make([]byte, 1+handshakeSize)
+	if _, err := io.ReadFull(r.r, c0c1); err != nil {
+		return
+	}
+	s0s1s2 := make([]byte, 1+handshakeSize*2)
+	s0s1s2[0] = rtmpVersion
+	copy(s0s1s2[1+handshakeSize:], c0c1[1:])
+	if _, err := conn.Write(s0s1s2); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(r.r, make([]byte, handshakeSize)); err != nil {
+		return
+	}
+
+	writeUint32 := func(typ uint8, v uint32) error {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, v)
+		return w.writeMessage(csidControl, &Message{Type: typ, Payload: b})
+	}
+	command := func(streamID uint32, vals ...interface{}) error {
+		b, err := EncodeAMF0(vals...)
+		if err != nil {
+			return err
+		}
+		return w.writeMessage(csidCommand, &Message{Type: TypeCommandAMF0, StreamID: streamID, Payload: b})
+	}
+	onStatus := func(level, code string) error {
+		return command(1, "onStatus", 0.0, nil, Object{"level": level, "code": code})
+	}
+
+	// A small chunk size to split the messages.
+	if err := writeUint32(TypeSetChunkSize, 60); err != nil {
+		return
+	}
+	w.chunkSize = 60
+	if err := writeUint32(TypeWindowAckSize, defaultWindowSize); err != nil {
+		return
+	}
+	if err := w.writeMessage(csidControl, &Message{Type: TypeUserControl, Payload: []byte{0, eventPingRequest, 0, 0, 0, 1}}); err != nil {
+		return
+	}
+
+	for {
+		m, err := r.readMessage()
+		if err != nil {
+			return
+		}
+		switch m.Type {
+		case TypeSetChunkSize:
+			r.chunkSize = binary.BigEndian.Uint32(m.Payload)
+			continue
+		case TypeCommandAMF0:
+		default:
+			continue
+		}
+
+		vals, err := DecodeAMF0(m.Payload)
+		if err != nil || len(vals) < 2 {
+			return
+		}
+		txn := vals[1]
+		switch vals[0] {
+		case "connect":
+			s.connectArgs <- vals[2:]
+			if len(vals) < 4 || vals[3] != s.ticket {
+				command(0, "_error", txn, nil, Object{"level": "error", "code": "NetConnection.Connect.Rejected", "description": "invalid ticket"})
+				return
+			}
+			command(0, "_result", txn, Object{"fmsVer": "FMS/3,5,7,7009"}, Object{"level": "status", "code": "NetConnection.Connect.Success"})
+		case "nlPlayNotice":
+			s.playNotices <- vals[3:]
+		case "createStream":
+			command(0, "_result", txn, nil, 1.0)
+		case "play":
+			if len(vals) < 4 || vals[3] != s.stream {
+				onStatus("error", "NetStream.Play.StreamNotFound")
+				continue
+			}
+			onStatus("status", "NetStream.Play.Reset")
+			onStatus("status", "NetStream.Play.Start")
+			for _, mm := range s.media {
+				if err := w.writeMessage(4, mm); err != nil {
+					return
+				}
+			}
+			onStatus("status", "NetStream.Play.Stop")
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package nico
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SessionUser is the user of the logged-in session.
+type SessionUser struct {
+	ID        int64
+	Nickname  string
+	IsPremium bool
+}
+
+type usersMeResponse struct {
+	Meta struct {
+		Status    int    `json:"status"`
+		ErrorCode string `json:"errorCode"`
+	} `json:"meta"`
+	Data struct {
+		User struct {
+			ID        json.Number `json:"id"`
+			Nickname  string      `json:"nickname"`
+			IsPremium bool        `json:"isPremium"`
+		} `json:"user"`
+	} `json:"data"`
+}
+
+// VerifySession verifies the user session and returns the logged-in user.
+// It returns ErrNotLoggedIn if the session is expired.
+func (c *Client) VerifySession(ctx context.Context) (*SessionUser, error) {
+	u, err := url.Parse(c.nvapiBaseRawurl)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "v1/users/me"
+
+	req, err := c.newAuthRequest(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Frontend-Id", "6")
+	req.Header.Set("X-Frontend-Version", "0")
+
+	resp, err := c.do(req, EndpointUsersMe)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrNotLoggedIn
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(EndpointUsersMe, resp)
+	}
+
+	var umr usersMeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&umr); err != nil {
+		return nil, err
+	}
+	id, err := umr.Data.User.ID.Int64()
+	if err != nil || id == 0 {
+		return nil, ErrNotLoggedIn
+	}
+	return &SessionUser{
+		ID:        id,
+		Nickname:  umr.Data.User.Nickname,
+		IsPremium: umr.Data.User.IsPremium,
+	}, nil
+}
+
+// Logout invalidates the user session on niconico
+// and removes the session from the client and its SessionStore.
+func (c *Client) Logout(ctx context.Context) error {
+	req, err := c.newAuthRequest(ctx, http.MethodGet, c.logoutRawurl, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(withoutRedirect(req), EndpointLogout)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newAPIError(EndpointLogout, resp)
+	}
+
+	c.setUserSession("")
+	if j, ok := c.Jar.(*Jar); ok {
+		j.remove("user_session")
+	}
+	// The invalidated session must not be restored.
+	return c.saveSession(ctx, "")
+}
+
+// remove expires the cookies of name in the jar.
+func (j *Jar) remove(name string) {
+	for _, c := range j.AllCookies() {
+		if c.Name != name {
+			continue
+		}
+		u := &url.URL{Scheme: "https", Host: strings.TrimPrefix(c.Domain, "."), Path: c.Path}
+		dc := &http.Cookie{Name: c.Name, Path: c.Path, MaxAge: -1}
+		if strings.HasPrefix(c.Domain, ".") {
+			dc.Domain = c.Domain
+		}
+		j.SetCookies(u, []*http.Cookie{dc})
+	}
+}
@@ -0,0 +1,239 @@
+package nico
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SessionStore loads and saves the user session of a Client.
+type SessionStore interface {
+	// LoadSession returns the saved user session.
+	// It returns the empty string if no session is saved.
+	LoadSession(ctx context.Context) (string, error)
+
+	// SaveSession saves the user session.
+	// The empty string is saved on Logout.
+	SaveSession(ctx context.Context, session string) error
+}
+
+// CredentialsProvider returns the mail and the password to login again
+// when the user session is expired.
+type CredentialsProvider func(ctx context.Context) (mail, password string, err error)
+
+// WithSessionStore sets the store that the client saves the user session to on Login.
+// The session is loaded from it before the first call that requires login
+// if UserSession is not set.
+func WithSessionStore(s SessionStore) Option {
+	return func(c *Client) { c.sessionStore = s }
+}
+
+// WithCredentialsProvider makes the client login again with the credentials from p
+// and retry the call once when the user session is expired.
+func WithCredentialsProvider(p CredentialsProvider) Option {
+	return func(c *Client) { c.credentials = p }
+}
+
+// RestoreSession sets the user session loaded from the SessionStore of c.
+func (c *Client) RestoreSession(ctx context.Context) error {
+	if c.sessionStore == nil {
+		return errors.New("session store is not set")
+	}
+	us, err := c.sessionStore.LoadSession(ctx)
+	if err != nil {
+		return err
+	}
+	if us != "" {
+		c.setUserSession(us)
+	}
+	return nil
+}
+
+// loadSession restores the session once if the client has no session.
+func (c *Client) loadSession(ctx context.Context) error {
+	if c.sessionStore == nil {
+		return nil
+	}
+	c.reloginMu.Lock()
+	defer c.reloginMu.Unlock()
+	if c.sessionLoaded {
+		return nil
+	}
+	if c.userSession() == "" {
+		if err := c.RestoreSession(ctx); err != nil {
+			return err
+		}
+	}
+	c.sessionLoaded = true
+	return nil
+}
+
+func (c *Client) saveSession(ctx context.Context, us string) error {
+	if c.sessionStore == nil {
+		return nil
+	}
+	return c.sessionStore.SaveSession(ctx, us)
+}
+
+// withRelogin calls fn and calls it again after login
+// if fn returns ErrNotLoggedIn and the client has the CredentialsProvider.
+func (c *Client) withRelogin(ctx context.Context, fn func() error) error {
+	stale := c.userSession()
+	err := fn()
+	if c.credentials == nil || !errors.Is(err, ErrNotLoggedIn) {
+		return err
+	}
+	if err := c.relogin(ctx, stale); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// relogin logins again unless another goroutine has already replaced the stale session.
+func (c *Client) relogin(ctx context.Context, stale string) error {
+	c.reloginMu.Lock()
+	defer c.reloginMu.Unlock()
+	if c.userSession() != stale {
+		return nil
+	}
+	mail, password, err := c.credentials(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = c.Login(ctx, mail, password)
+	return err
+}
+
+// MemorySessionStore is a SessionStore that keeps the session in memory.
+type MemorySessionStore struct {
+	mu      sync.Mutex
+	session string
+}
+
+// LoadSession implements the LoadSession method of the SessionStore interface.
+func (s *MemorySessionStore) LoadSession(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.session, nil
+}
+
+// SaveSession implements the SaveSession method of the SessionStore interface.
+func (s *MemorySessionStore) SaveSession(ctx context.Context, session string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = session
+	return nil
+}
+
+// Parameters of the encryption of FileSessionStore.
+const (
+	fileSessionMagic = "NICOSESS1"
+	fileSessionIter  = 100000
+	fileSessionSalt  = 16
+)
+
+// ErrInvalidPassphrase is returned when the session file cannot be decrypted.
+var ErrInvalidPassphrase = errors.New("invalid passphrase or corrupted session file")
+
+// FileSessionStore is a SessionStore that saves the session to a file
+// encrypted with AES-GCM by the key derived from the passphrase.
+type FileSessionStore struct {
+	mu         sync.Mutex
+	name       string
+	passphrase string
+}
+
+// NewFileSessionStore returns new FileSessionStore of the file of name.
+func NewFileSessionStore(name, passphrase string) *FileSessionStore {
+	return &FileSessionStore{name: name, passphrase: passphrase}
+}
+
+// LoadSession implements the LoadSession method of the SessionStore interface.
+// It returns the empty string if the file does not exist.
+func (s *FileSessionStore) LoadSession(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := ioutil.ReadFile(s.name)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(b, []byte(fileSessionMagic)) || len(b) < len(fileSessionMagic)+fileSessionSalt {
+		return "", ErrInvalidPassphrase
+	}
+	b = b[len(fileSessionMagic):]
+	gcm, err := s.gcm(b[:fileSessionSalt])
+	if err != nil {
+		return "", err
+	}
+	b = b[fileSessionSalt:]
+	if len(b) < gcm.NonceSize() {
+		return "", ErrInvalidPassphrase
+	}
+	plain, err := gcm.Open(nil, b[:gcm.NonceSize()], b[gcm.NonceSize():], []byte(fileSessionMagic))
+	if err != nil {
+		return "", ErrInvalidPassphrase
+	}
+	return string(plain), nil
+}
+
+// SaveSession implements the SaveSession method of the SessionStore interface.
+func (s *FileSessionStore) SaveSession(ctx context.Context, session string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	salt := make([]byte, fileSessionSalt)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	gcm, err := s.gcm(salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	b := append([]byte(fileSessionMagic), salt...)
+	b = append(b, nonce...)
+	b = gcm.Seal(b, nonce, []byte(session), []byte(fileSessionMagic))
+
+	// Replace the file by rename not to leave a truncated file on failure.
+	f, err := ioutil.TempFile(filepath.Dir(s.name), filepath.Base(s.name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.name)
+}
+
+func (s *FileSessionStore) gcm(salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, s.passphrase, salt, fileSessionIter, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,215 @@
+package nico
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFileSessionStore(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "session")
+	s := NewFileSessionStore(name, "passphrase")
+
+	us, err := s.LoadSession(ctx)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if us != "" {
+		t.Fatalf("want empty but %q", us)
+	}
+
+	if err := s.SaveSession(ctx, "foobarbaz"); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if strings.Contains(string(b), "foobarbaz") {
+		t.Fatalf("session should be encrypted")
+	}
+	if fis, err := ioutil.ReadDir(filepath.Dir(name)); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	} else if len(fis) != 1 {
+		t.Fatalf("want only the session file but %d files", len(fis))
+	}
+
+	us, err = NewFileSessionStore(name, "passphrase").LoadSession(ctx)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if us != "foobarbaz" {
+		t.Fatalf("want %q but %q", "foobarbaz", us)
+	}
+
+	if _, err := NewFileSessionStore(name, "wrong").LoadSession(ctx); err != ErrInvalidPassphrase {
+		t.Fatalf("want %v but %v", ErrInvalidPassphrase, err)
+	}
+}
+
+func TestClient_RestoreSession(t *testing.T) {
+	s := &MemorySessionStore{}
+	s.SaveSession(context.Background(), "foobarbaz")
+
+	c := NewClient(WithSessionStore(s))
+	if err := c.RestoreSession(context.Background()); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if c.UserSession != "foobarbaz" {
+		t.Fatalf("want %q but %q", "foobarbaz", c.UserSession)
+	}
+
+	if err := NewClient().RestoreSession(context.Background()); err == nil {
+		t.Fatalf("should be fail: %v", err)
+	}
+}
+
+func TestClient_Relogin(t *testing.T) {
+	var logins int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			atomic.AddInt32(&logins, 1)
+			if r.FormValue("password") != "bar" {
+				http.Redirect(w, r, "/login", http.StatusFound)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "user_session", Value: "new-session", Path: "/"})
+			http.Redirect(w, r, "/", http.StatusFound)
+		case "/api/getplayerstatus":
+			if c, err := r.Cookie("user_session"); err != nil || c.Value != "new-session" {
+				io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><getplayerstatus status="fail"><error><code>notlogin</code></error></getplayerstatus>`)
+				return
+			}
+			io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><getplayerstatus status="ok"></getplayerstatus>`)
+		}
+	}))
+	defer ts.Close()
+
+	s := &MemorySessionStore{}
+	c := NewClient(
+		WithLoginURL(ts.URL+"/login"),
+		WithLiveBaseURL(ts.URL),
+		WithSessionStore(s),
+		WithCredentialsProvider(func(ctx context.Context) (string, string, error) {
+			return "foo@foo.com", "bar", nil
+		}),
+	)
+	c.UserSession = "old-session"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetPlayerStatus(context.Background(), "lv123456789"); err != nil {
+				t.Errorf("should not be fail: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&logins); n != 1 {
+		t.Fatalf("want %d but %d", 1, n)
+	}
+	if us, _ := s.LoadSession(context.Background()); us != "new-session" {
+		t.Fatalf("want %q but %q", "new-session", us)
+	}
+
+	c = NewClient(
+		WithLoginURL(ts.URL+"/login"),
+		WithLiveBaseURL(ts.URL),
+		WithCredentialsProvider(func(ctx context.Context) (string, string, error) {
+			return "foo@foo.com", "wrong", nil
+		}),
+	)
+	if _, err := c.GetPlayerStatus(context.Background(), "lv123456789"); !errors.Is(err, ErrLoginFailed) {
+		t.Fatalf("want %v but %v", ErrLoginFailed, err)
+	}
+}
+
+func TestClient_LoadSession(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("user_session"); err != nil || c.Value != "foobarbaz" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, "postkey=foo")
+	}))
+	defer ts.Close()
+
+	s := &MemorySessionStore{}
+	s.SaveSession(context.Background(), "foobarbaz")
+	c := NewClient(WithLiveBaseURL(ts.URL), WithSessionStore(s))
+	if _, err := c.GetPostkey(context.Background(), 1); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if c.UserSession != "foobarbaz" {
+		t.Fatalf("want %q but %q", "foobarbaz", c.UserSession)
+	}
+}
+
+func TestClient_ReloginOnEveryEndpoint(t *testing.T) {
+	var logins int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/secure/login" {
+			atomic.AddInt32(&logins, 1)
+			http.SetCookie(w, &http.Cookie{Name: "user_session", Value: "new-session", Path: "/"})
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		loggedIn := false
+		if c, err := r.Cookie("user_session"); err == nil && c.Value == "new-session" {
+			loggedIn = true
+		}
+		switch r.URL.Path {
+		case "/api/getpostkey":
+			if !loggedIn {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			io.WriteString(w, "postkey=foo")
+		case "/motion/co1234567":
+			if !loggedIn {
+				http.Redirect(w, r, "/secure/login_form", http.StatusFound)
+				return
+			}
+			if r.FormValue("mode") != "commit" {
+				t.Errorf("want %q but %q", "commit", r.FormValue("mode"))
+			}
+			http.Redirect(w, r, "/motion/co1234567/done", http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+
+	newClient := func() *Client {
+		c := NewClient(
+			WithLoginURL(ts.URL+"/secure/login"),
+			WithLiveBaseURL(ts.URL),
+			WithCommunityBaseURL(ts.URL),
+			WithCredentialsProvider(func(ctx context.Context) (string, string, error) {
+				return "foo@foo.com", "bar", nil
+			}),
+		)
+		c.UserSession = "old-session"
+		return c
+	}
+
+	if _, err := newClient().GetPostkey(context.Background(), 1); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if err := newClient().FollowCommunity(context.Background(), "co1234567"); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if n := atomic.LoadInt32(&logins); n != 2 {
+		t.Fatalf("want %d but %d", 2, n)
+	}
+}
@@ -0,0 +1,102 @@
+package nico
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifySession(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/users/me" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.Header.Get("X-Frontend-Id"); got == "" {
+			t.Errorf("X-Frontend-Id should be set")
+		}
+		us, err := r.Cookie("user_session")
+		if err != nil || us.Value != "user-session" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"meta":{"status":401,"errorCode":"UNAUTHORIZED"}}`)
+			return
+		}
+		io.WriteString(w, `{"meta":{"status":200},"data":{"user":{"id":2525,"nickname":"foo","isPremium":true}}}`)
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithNvapiBaseURL(ts.URL))
+	if _, err := c.VerifySession(context.Background()); !errors.Is(err, ErrNotLoggedIn) {
+		t.Fatalf("want %v but %v", ErrNotLoggedIn, err)
+	}
+
+	c.UserSession = "user-session"
+	su, err := c.VerifySession(context.Background())
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if su.ID != 2525 {
+		t.Fatalf("want %d but %d", 2525, su.ID)
+	}
+	if su.Nickname != "foo" {
+		t.Fatalf("want %q but %q", "foo", su.Nickname)
+	}
+	if !su.IsPremium {
+		t.Fatalf("should be premium")
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("user_session"); err != nil {
+			t.Errorf("user_session should be sent: %v", err)
+		}
+		http.SetCookie(w, &http.Cookie{Name: "user_session", Value: "deleted", Path: "/", MaxAge: -1})
+		http.Redirect(w, r, "/", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithLogoutURL(ts.URL + "/secure/logout"))
+	c.Jar.SetCookies(&url.URL{Scheme: "https", Host: "live.nicovideo.jp"}, []*http.Cookie{
+		{Name: "user_session", Value: "user-session", Domain: ".nicovideo.jp", Path: "/"},
+	})
+	c.UserSession = "user-session"
+	if err := c.Logout(context.Background()); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if c.UserSession != "" {
+		t.Fatalf("UserSession should be empty: %q", c.UserSession)
+	}
+	if hasCookie(c.Jar, &url.URL{Scheme: "https", Host: "live.nicovideo.jp", Path: "/"}, "user_session") {
+		t.Fatalf("user_session should be removed from the jar")
+	}
+}
+
+func TestLogout_SessionStore(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "session")
+	if err := NewFileSessionStore(name, "passphrase").SaveSession(ctx, "user-session"); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	c := NewClient(WithLogoutURL(ts.URL+"/secure/logout"), WithSessionStore(NewFileSessionStore(name, "passphrase")))
+	if err := c.Logout(ctx); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	us, err := NewFileSessionStore(name, "passphrase").LoadSession(ctx)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	if us != "" {
+		t.Fatalf("want empty but %q", us)
+	}
+}
@@ -0,0 +1,409 @@
+package nico
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultReconnectPolicy is a recommended ReconnectPolicy.
+var DefaultReconnectPolicy = ReconnectPolicy{
+	BaseDelay: time.Second,
+	MaxDelay:  30 * time.Second,
+}
+
+// ReconnectPolicy is a policy to redial the comment server
+// when the connection of StreamingComment is lost.
+type ReconnectPolicy struct {
+	// MaxAttempts is the maximum number of consecutive attempts to redial.
+	// Zero means unlimited.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first attempt.
+	// The delay doubles on every attempt with jitter.
+	// The one of DefaultReconnectPolicy is used if it is not positive.
+	BaseDelay time.Duration
+
+	// MaxDelay is the upper limit of the delay.
+	// The one of DefaultReconnectPolicy is used if it is not positive.
+	MaxDelay time.Duration
+}
+
+func (p ReconnectPolicy) backoff(attempt int) time.Duration {
+	// Zero delays would redial an unreachable server in a tight loop.
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultReconnectPolicy.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultReconnectPolicy.MaxDelay
+	}
+	return RetryPolicy{BaseDelay: p.BaseDelay, MaxDelay: p.MaxDelay}.backoff(attempt)
+}
+
+// StreamOption is a function that configures StreamingComment.
+type StreamOption func(*streamConfig)
+
+type streamConfig struct {
+	reconnect    *ReconnectPolicy
+	backfill     time.Duration
+	keepalive    time.Duration
+	stallTimeout time.Duration
+
+	// bufferSize and overflow are used by Subscribe.
+	bufferSize int
+	overflow   OverflowPolicy
+}
+
+// WithReconnect makes StreamingComment redial the comment server by p
+// when the connection is lost. The stream is resumed from the comment
+// after the last received one and duplicated chats are dropped.
+func WithReconnect(p ReconnectPolicy) StreamOption {
+	return func(cfg *streamConfig) { cfg.reconnect = &p }
+}
+
+// WithBackfill makes StreamingComment detect the gaps of the numbers of the chats
+// and fetch the missing chats by a side connection that lives for timeout at most.
+// The recovered chats are sent in order before the chat that revealed the gap,
+// and GapDetected is sent for the chats that cannot be recovered.
+func WithBackfill(timeout time.Duration) StreamOption {
+	return func(cfg *streamConfig) { cfg.backfill = timeout }
+}
+
+// WithKeepalive makes StreamingComment send an empty frame to the comment server
+// every interval to keep the connection alive.
+func WithKeepalive(interval time.Duration) StreamOption {
+	return func(cfg *streamConfig) { cfg.keepalive = interval }
+}
+
+// WithStallTimeout makes StreamingComment send Stalled when nothing is received
+// for timeout. The connection is redialed if WithReconnect is also given,
+// otherwise the stream keeps waiting and Stalled is sent every timeout.
+func WithStallTimeout(timeout time.Duration) StreamOption {
+	return func(cfg *streamConfig) { cfg.stallTimeout = timeout }
+}
+
+// Stalled is sent by StreamingComment when nothing is received for Idle.
+type Stalled struct {
+	Idle time.Duration
+}
+
+func (s *Stalled) comment() {}
+
+// maxBackfill is the maximum number of the chats fetched by a backfill.
+const maxBackfill = 1000
+
+// backfillMargin is the number of the chats fetched additionally by a backfill
+// for the chats posted after the gap is detected.
+const backfillMargin = 100
+
+// GapDetected is sent by StreamingComment when the chats of numbers from From to To
+// are missing and cannot be recovered. Err is the cause if the backfill failed.
+type GapDetected struct {
+	Thread   int64
+	From, To int64
+	Err      error
+}
+
+func (g *GapDetected) comment() {}
+
+// Disconnected is sent by StreamingComment when the connection is lost
+// and it is going to reconnect.
+type Disconnected struct {
+	Err error
+}
+
+func (d *Disconnected) comment() {}
+
+// Reconnected is sent by StreamingComment when the connection is recovered.
+// Attempts is the number of the attempts to redial.
+type Reconnected struct {
+	Attempts int
+}
+
+func (r *Reconnected) comment() {}
+
+// commentStream is the state of StreamingComment.
+type commentStream struct {
+	lc      *LiveClient
+	cfg     streamConfig
+	ch      chan<- Comment
+	seen    *chatSet
+	resFrom int64
+	lastNo  int64
+
+	// firstNo is the number of the first chat expected by the thread, 0 if it is unknown.
+	firstNo int64
+}
+
+// sendThread sends the request to receive the comments of thread.
+func sendThread(conn net.Conn, thread, resFrom int64) error {
+	b, err := xml.Marshal(SendThread{Thread: thread, Version: 20061206, ResFrom: resFrom})
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(append(b, 0))
+	return err
+}
+
+func (s *commentStream) run(ctx context.Context, conn net.Conn) {
+	redialed := false
+	defer func() {
+		if redialed {
+			conn.Close()
+		}
+	}()
+
+	for {
+		err := s.read(ctx, conn)
+		if ctx.Err() != nil {
+			// The receiver may be gone, so the error is sent only if the buffer has room.
+			select {
+			case s.ch <- &CommentError{ctx.Err()}:
+			default:
+			}
+			return
+		}
+		if s.cfg.reconnect == nil {
+			sendComment(ctx, s.ch, &CommentError{err})
+			return
+		}
+
+		if !sendComment(ctx, s.ch, &Disconnected{Err: err}) {
+			return
+		}
+		newConn, attempts, err := s.redial(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				sendComment(ctx, s.ch, &CommentError{err})
+			}
+			return
+		}
+		// The lost connection is no longer used.
+		conn.Close()
+		conn, redialed = newConn, true
+		if !sendComment(ctx, s.ch, &Reconnected{Attempts: attempts}) {
+			return
+		}
+	}
+}
+
+// read sends the comments read from conn until reading fails.
+func (s *commentStream) read(ctx context.Context, conn net.Conn) error {
+	// Unblock the read on cancel. The connection is kept for PostComment
+	// and the next StreamingComment, so the deadline is cleared on return.
+	done := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Now())
+		close(done)
+	})
+	defer func() {
+		if !stop() {
+			<-done
+		}
+		conn.SetReadDeadline(time.Time{})
+	}()
+
+	if s.cfg.keepalive > 0 {
+		defer keepalive(conn, s.cfg.keepalive)()
+	}
+
+	dec := NewCommentDecoder(conn)
+	for {
+		if s.cfg.stallTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.cfg.stallTimeout))
+			// Check after setting the deadline not to overwrite the one set on cancel.
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+		cmt, err := dec.Decode()
+		if err != nil {
+			var ne net.Error
+			if s.cfg.stallTimeout > 0 && ctx.Err() == nil && errors.As(err, &ne) && ne.Timeout() {
+				if !sendComment(ctx, s.ch, &Stalled{Idle: s.cfg.stallTimeout}) {
+					return ctx.Err()
+				}
+				if s.cfg.reconnect != nil {
+					return ErrStalled
+				}
+				continue
+			}
+			return err
+		}
+		switch v := cmt.(type) {
+		case *Thread:
+			if s.lastNo == 0 && v.Thread == s.lc.PlayerStatus.Ms.Thread {
+				s.firstNo = firstChatNo(s.resFrom, v.LastRes)
+			}
+		case *Chat:
+			if !s.seen.add(chatKey{v.Thread, v.No}) {
+				continue
+			}
+			if next := s.nextNo(); s.cfg.backfill > 0 && next > 0 && v.No > next {
+				if !s.fill(ctx, next, v.No) {
+					return ctx.Err()
+				}
+			}
+			if v.No > s.lastNo {
+				s.lastNo = v.No
+			}
+		}
+		if !sendComment(ctx, s.ch, cmt) {
+			return ctx.Err()
+		}
+	}
+}
+
+// nextNo returns the number of the chat expected next, 0 if it is unknown.
+func (s *commentStream) nextNo() int64 {
+	if s.lastNo > 0 {
+		return s.lastNo + 1
+	}
+	return s.firstNo
+}
+
+// firstChatNo returns the number of the first chat sent for resFrom
+// when the number of the last chat of the thread is lastRes. It returns 0 if it is unknown.
+func firstChatNo(resFrom, lastRes int64) int64 {
+	switch {
+	case resFrom > 0:
+		return resFrom
+	case resFrom < 0:
+		if no := lastRes + resFrom + 1; no > 1 {
+			return no
+		}
+		return 1
+	}
+	return 0
+}
+
+// keepalive sends an empty frame to conn every interval until the returned function is called.
+func keepalive(conn net.Conn, interval time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				// A write error is detected by the read.
+				conn.Write([]byte{0})
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// redial connects to the comment server again and resumes the thread
+// from the comment after the last received one.
+func (s *commentStream) redial(ctx context.Context) (net.Conn, int, error) {
+	p := s.cfg.reconnect
+	ms := s.lc.PlayerStatus.Ms
+	for attempt := 1; p.MaxAttempts <= 0 || attempt <= p.MaxAttempts; attempt++ {
+		if err := sleep(ctx, p.backoff(attempt)); err != nil {
+			return nil, attempt, err
+		}
+		conn, err := s.lc.dial(ctx, ms.Addr, ms.Port)
+		if err != nil {
+			continue
+		}
+		resFrom := s.resFrom
+		if s.lastNo > 0 {
+			resFrom = s.lastNo + 1
+		}
+		if err := sendThread(conn, ms.Thread, resFrom); err != nil {
+			conn.Close()
+			continue
+		}
+		s.lc.setConn(conn)
+		return conn, attempt, nil
+	}
+	return nil, p.MaxAttempts, ErrReconnectFailed
+}
+
+// fill sends the chats of numbers from from to before-1 fetched by backfill,
+// and GapDetected for the missing ones. It reports whether the receiver is alive.
+func (s *commentStream) fill(ctx context.Context, from, before int64) bool {
+	thread := s.lc.PlayerStatus.Ms.Thread
+	var chats map[int64]*Chat
+	var err error
+	if before-from > maxBackfill {
+		err = fmt.Errorf("gap of %d chats is too large to backfill", before-from)
+	} else {
+		chats, err = s.backfill(ctx, from, before)
+	}
+
+	gapFrom := int64(0)
+	for no := from; no <= before; no++ {
+		chat, ok := chats[no]
+		if no < before && !ok {
+			if gapFrom == 0 {
+				gapFrom = no
+			}
+			continue
+		}
+		if gapFrom != 0 {
+			if !sendComment(ctx, s.ch, &GapDetected{Thread: thread, From: gapFrom, To: no - 1, Err: err}) {
+				return false
+			}
+			gapFrom = 0
+		}
+		if no < before && s.seen.add(chatKey{chat.Thread, chat.No}) {
+			if !sendComment(ctx, s.ch, chat) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// backfill fetches the chats of numbers from from to before-1 by a side connection.
+// It returns the chats fetched until the timeout even if some are missing.
+func (s *commentStream) backfill(ctx context.Context, from, before int64) (map[int64]*Chat, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.backfill)
+	defer cancel()
+
+	ms := s.lc.PlayerStatus.Ms
+	conn, err := s.lc.dial(ctx, ms.Addr, ms.Port)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	// The negative res_from fetches the latest chats, which include the chats
+	// after the gap received already.
+	if err := sendThread(conn, ms.Thread, -(before - from + 1 + backfillMargin)); err != nil {
+		return nil, err
+	}
+
+	chats := map[int64]*Chat{}
+	dec := NewCommentDecoder(conn)
+	for int64(len(chats)) < before-from {
+		cmt, err := dec.Decode()
+		if err != nil {
+			if ctx.Err() != nil {
+				return chats, ctx.Err()
+			}
+			return chats, err
+		}
+		chat, ok := cmt.(*Chat)
+		if !ok || chat.Thread != ms.Thread {
+			continue
+		}
+		if chat.No >= before {
+			// The history is over.
+			break
+		}
+		if chat.No >= from {
+			chats[chat.No] = chat
+		}
+	}
+	return chats, nil
+}
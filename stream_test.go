@@ -0,0 +1,467 @@
+package nico
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// scriptedConn is a script of a connection of newScriptedCommentServer.
+// The connection is closed after the frames if close is true.
+// If hold is not nil, closing waits until hold is closed.
+type scriptedConn struct {
+	frames []string
+	close  bool
+	hold   chan struct{}
+}
+
+// newScriptedCommentServer accepts the connections in order and serves them by script.
+// The received thread requests are sent to requests.
+
+func newScriptedCommentServer(t *testing.T, requests chan<- SendThread, script ...scriptedConn) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	go func() {
+		for _, sc := range script {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn, sc scriptedConn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				b, err := r.ReadBytes(0)
+				if err != nil {
+					return
+				}
+				var req SendThread
+				xml.Unmarshal(b[:len(b)-1], &req)
+				requests <- req
+				for _, f := range sc.frames {
+					if _, err := conn.Write(append([]byte(f), 0)); err != nil {
+						return
+					}
+				}
+				if !sc.close {
+					r.ReadBytes(0)
+				} else if sc.hold != nil {
+					<-sc.hold
+				}
+			}(conn, sc)
+		}
+	}()
+	return ln
+}
+
+func newTestLiveClient(t *testing.T, ln net.Listener) *LiveClient {
+	t.Helper()
+	c := NewClient(WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, ln.Addr().String())
+	}))
+	ps := &PlayerStatus{Ms: Ms{Addr: "omsg101.live.nicovideo.jp", Port: 2805, Thread: 100}}
+	conn, err := c.dial(context.Background(), ps.Ms.Addr, ps.Ms.Port)
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &LiveClient{Client: c, PlayerStatus: ps, conn: conn}
+}
+
+func chat(no int64) string {
+	return fmt.Sprintf(`<chat thread="100" no="%d">c%d</chat>`, no, no)
+}
+
+func TestLiveClient_StreamingCommentReconnect(t *testing.T) {
+	requests := make(chan SendThread, 10)
+	ln := newScriptedCommentServer(t, requests,
+		scriptedConn{frames: []string{`<thread thread="100" last_res="2"/>`, chat(1), chat(2)}, close: true},
+		scriptedConn{frames: []string{`<thread thread="100" last_res="3"/>`, chat(2), chat(3)}},
+	)
+	defer ln.Close()
+	lc := newTestLiveClient(t, ln)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := lc.StreamingComment(ctx, -10, WithReconnect(ReconnectPolicy{BaseDelay: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < 7 {
+		select {
+		case cmt := <-ch:
+			switch v := cmt.(type) {
+			case *Thread:
+				got = append(got, "thread")
+			case *Chat:
+				got = append(got, v.Comment)
+			case *Disconnected:
+				got = append(got, "disconnected")
+			case *Reconnected:
+				got = append(got, fmt.Sprintf("reconnected:%d", v.Attempts))
+			case *CommentError:
+				t.Fatalf("should not be fail: %v", v)
+			}
+		case <-timeout:
+			t.Fatalf("timed out: %v", got)
+		}
+	}
+	want := []string{"thread", "c1", "c2", "disconnected", "reconnected:1", "thread", "c3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v but %v", want, got)
+	}
+
+	if req := <-requests; req.ResFrom != -10 {
+		t.Fatalf("want %d but %d", -10, req.ResFrom)
+	}
+	if req := <-requests; req.ResFrom != 3 || req.Thread != 100 {
+		t.Fatalf("want res_from %d but %+v", 3, req)
+	}
+	if lc.getConn() == nil {
+		t.Fatalf("the connection should be replaced")
+	}
+
+	cancel()
+	for cmt := range ch {
+		if ce, ok := cmt.(*CommentError); !ok || !errors.Is(ce, context.Canceled) {
+			t.Fatalf("want %v but %+v", context.Canceled, cmt)
+		}
+	}
+}
+
+func TestLiveClient_StreamingCommentReconnectFailed(t *testing.T) {
+	requests := make(chan SendThread, 10)
+	hold := make(chan struct{})
+	ln := newScriptedCommentServer(t, requests, scriptedConn{frames: []string{chat(1)}, close: true, hold: hold})
+	lc := newTestLiveClient(t, ln)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ch, err := lc.StreamingComment(ctx, 0, WithReconnect(ReconnectPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+	<-requests
+	ln.Close()
+	close(hold)
+
+	var last Comment
+	for cmt := range ch {
+		last = cmt
+	}
+	if ce, ok := last.(*CommentError); !ok || !errors.Is(ce, ErrReconnectFailed) {
+		t.Fatalf("want %v but %+v", ErrReconnectFailed, last)
+	}
+}
+
+func TestLiveClient_StreamingCommentBackfill(t *testing.T) {
+	requests := make(chan SendThread, 10)
+	ln := newScriptedCommentServer(t, requests,
+		scriptedConn{frames: []string{`<thread thread="100"/>`, chat(1), chat(2), chat(5), chat(6)}},
+		// The chat 4 is deleted.
+		scriptedConn{frames: []string{`<thread thread="100"/>`, chat(1), chat(2), chat(3), chat(5), chat(6)}},
+	)
+	defer ln.Close()
+	lc := newTestLiveClient(t, ln)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := lc.StreamingComment(ctx, 0, WithBackfill(5*time.Second))
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < 6 {
+		select {
+		case cmt := <-ch:
+			switch v := cmt.(type) {
+			case *Chat:
+				got = append(got, v.Comment)
+			case *GapDetected:
+				if v.Err != nil {
+					t.Fatalf("should not be fail: %v", v.Err)
+				}
+				got = append(got, fmt.Sprintf("gap:%d-%d", v.From, v.To))
+			case *CommentError:
+				t.Fatalf("should not be fail: %v", v)
+			}
+		case <-timeout:
+			t.Fatalf("timed out: %v", got)
+		}
+	}
+	want := []string{"c1", "c2", "c3", "gap:4-4", "c5", "c6"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v but %v", want, got)
+	}
+
+	<-requests
+	if req := <-requests; req.ResFrom != -103 {
+		t.Fatalf("want %d but %d", -103, req.ResFrom)
+	}
+}
+
+func TestLiveClient_StreamingCommentBackfillFirst(t *testing.T) {
+	requests := make(chan SendThread, 10)
+	ln := newScriptedCommentServer(t, requests,
+		// The chats from 3 are expected for res_from -3.
+		scriptedConn{frames: []string{`<thread thread="100" last_res="5"/>`, chat(4), chat(5)}},
+		scriptedConn{frames: []string{`<thread thread="100" last_res="5"/>`, chat(1), chat(2), chat(3), chat(4), chat(5)}},
+	)
+	defer ln.Close()
+	lc := newTestLiveClient(t, ln)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := lc.StreamingComment(ctx, -3, WithBackfill(5*time.Second))
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < 3 {
+		select {
+		case cmt := <-ch:
+			switch v := cmt.(type) {
+			case *Chat:
+				got = append(got, v.Comment)
+			case *GapDetected:
+				t.Fatalf("should not detect the gap: %+v", v)
+			case *CommentError:
+				t.Fatalf("should not be fail: %v", v)
+			}
+		case <-timeout:
+			t.Fatalf("timed out: %v", got)
+		}
+	}
+	want := []string{"c3", "c4", "c5"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v but %v", want, got)
+	}
+
+	<-requests
+	if req := <-requests; req.ResFrom != -102 {
+		t.Fatalf("want %d but %d", -102, req.ResFrom)
+	}
+}
+
+func TestLiveClient_StreamingCommentBackfillTimeout(t *testing.T) {
+	requests := make(chan SendThread, 10)
+	ln := newScriptedCommentServer(t, requests,
+		scriptedConn{frames: []string{chat(1), chat(4), chat(5)}},
+		scriptedConn{},
+	)
+	defer ln.Close()
+	lc := newTestLiveClient(t, ln)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := lc.StreamingComment(ctx, 0, WithBackfill(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	var got []Comment
+	timeout := time.After(5 * time.Second)
+	for len(got) < 4 {
+		select {
+		case cmt := <-ch:
+			got = append(got, cmt)
+		case <-timeout:
+			t.Fatalf("timed out: %v", got)
+		}
+	}
+	g, ok := got[1].(*GapDetected)
+	if !ok || g.From != 2 || g.To != 3 || g.Thread != 100 || !errors.Is(g.Err, context.DeadlineExceeded) {
+		t.Fatalf("want the gap of 2-3 but %+v", got[1])
+	}
+	if c, ok := got[3].(*Chat); !ok || c.No != 5 {
+		t.Fatalf("want the chat 5 but %+v", got[3])
+	}
+}
+
+func TestLiveClient_StreamingCommentStallReconnect(t *testing.T) {
+	requests := make(chan SendThread, 10)
+	ln := newScriptedCommentServer(t, requests,
+		scriptedConn{frames: []string{chat(1)}},
+		scriptedConn{frames: []string{chat(2)}},
+	)
+	defer ln.Close()
+	lc := newTestLiveClient(t, ln)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := lc.StreamingComment(ctx, 0,
+		WithStallTimeout(50*time.Millisecond),
+		WithReconnect(ReconnectPolicy{BaseDelay: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < 5 {
+		select {
+		case cmt := <-ch:
+			switch v := cmt.(type) {
+			case *Chat:
+				got = append(got, v.Comment)
+			case *Stalled:
+				got = append(got, fmt.Sprintf("stalled:%v", v.Idle))
+			case *Disconnected:
+				if !errors.Is(v.Err, ErrStalled) {
+					t.Fatalf("want %v but %v", ErrStalled, v.Err)
+				}
+				got = append(got, "disconnected")
+			case *Reconnected:
+				got = append(got, "reconnected")
+			case *CommentError:
+				t.Fatalf("should not be fail: %v", v)
+			}
+		case <-timeout:
+			t.Fatalf("timed out: %v", got)
+		}
+	}
+	want := []string{"c1", "stalled:50ms", "disconnected", "reconnected", "c2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v but %v", want, got)
+	}
+}
+
+func TestLiveClient_StreamingCommentStall(t *testing.T) {
+	requests := make(chan SendThread, 10)
+	ln := newScriptedCommentServer(t, requests, scriptedConn{})
+	defer ln.Close()
+	lc := newTestLiveClient(t, ln)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := lc.StreamingComment(ctx, 0, WithStallTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	// The stream is kept without WithReconnect.
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < 2; i++ {
+		select {
+		case cmt := <-ch:
+			if _, ok := cmt.(*Stalled); !ok {
+				t.Fatalf("want *Stalled but %+v", cmt)
+			}
+		case <-timeout:
+			t.Fatalf("timed out")
+		}
+	}
+
+	cancel()
+	for cmt := range ch {
+		if _, ok := cmt.(*Stalled); ok {
+			continue
+		}
+		if ce, ok := cmt.(*CommentError); !ok || !errors.Is(ce, context.Canceled) {
+			t.Fatalf("want %v but %+v", context.Canceled, cmt)
+		}
+	}
+}
+
+func TestLiveClient_StreamingCommentKeepalive(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	lc := &LiveClient{Client: NewClient(), PlayerStatus: &PlayerStatus{}, conn: client}
+
+	received := make(chan []byte, 10)
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := server.Read(buf)
+			if err != nil {
+				return
+			}
+			received <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := lc.StreamingComment(ctx, 0, WithKeepalive(10*time.Millisecond)); err != nil {
+		t.Fatalf("should not be fail: %v", err)
+	}
+
+	<-received
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < 2; i++ {
+		select {
+		case b := <-received:
+			if string(b) != "\x00" {
+				t.Fatalf("want %q but %q", "\x00", b)
+			}
+		case <-timeout:
+			t.Fatalf("timed out")
+		}
+	}
+}
+
+func TestLiveClient_StreamingCommentRestart(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	lc := &LiveClient{Client: NewClient(), PlayerStatus: &PlayerStatus{Ms: Ms{Thread: 100}}, conn: client}
+
+	// The server sends a chat for every thread request.
+	go func() {
+		r := bufio.NewReader(server)
+		for no := int64(1); ; no++ {
+			if _, err := r.ReadBytes(0); err != nil {
+				return
+			}
+			if _, err := server.Write(append([]byte(chat(no)), 0)); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := int64(1); i <= 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch, err := lc.StreamingComment(ctx, 0)
+		if err != nil {
+			t.Fatalf("should not be fail: %v", err)
+		}
+		select {
+		case cmt := <-ch:
+			if c, ok := cmt.(*Chat); !ok || c.No != i {
+				t.Fatalf("want chat %d but %+v", i, cmt)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out")
+		}
+		cancel()
+		for range ch {
+		}
+	}
+}
+
+func TestReconnectPolicy_Backoff(t *testing.T) {
+	var p ReconnectPolicy
+	for attempt := 1; attempt <= 100; attempt++ {
+		d := p.backoff(attempt)
+		if d < DefaultReconnectPolicy.BaseDelay/2 || d > DefaultReconnectPolicy.MaxDelay {
+			t.Fatalf("want the delay between %v and %v but %v", DefaultReconnectPolicy.BaseDelay/2, DefaultReconnectPolicy.MaxDelay, d)
+		}
+	}
+}
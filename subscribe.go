@@ -0,0 +1,199 @@
+package nico
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultSubscribeBuffer is the buffer size of Subscribe without WithBuffer.
+const DefaultSubscribeBuffer = 100
+
+// OverflowPolicy is a behavior of Subscribe when the buffer is full.
+type OverflowPolicy int
+
+// Overflow policies.
+const (
+	// OverflowBlock stops reading the comment server until the buffer has room.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest drops the oldest comment in the buffer.
+	OverflowDropOldest
+
+	// OverflowFail ends the subscription with ErrCommentOverflow.
+	OverflowFail
+)
+
+// WithBuffer sets the number of the comments buffered for the handler of Subscribe
+// and the policy when the buffer is full. A size less than 1 is treated as 1.
+func WithBuffer(size int, policy OverflowPolicy) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.bufferSize = size
+		cfg.overflow = policy
+	}
+}
+
+// CommentHandler handles the comments of Subscribe.
+type CommentHandler interface {
+	OnThread(*Thread)
+	OnChat(*Chat)
+	OnChatResult(*ChatResult)
+	OnError(error)
+}
+
+// CommentEventHandler is an optional interface of CommentHandler that handles
+// the other comments such as RawFrame, GapDetected, Stalled, Disconnected and Reconnected.
+type CommentEventHandler interface {
+	OnEvent(Comment)
+}
+
+// CommentHandlerFuncs is an adapter to use functions as CommentHandler.
+// Nil functions are not called.
+type CommentHandlerFuncs struct {
+	Thread     func(*Thread)
+	Chat       func(*Chat)
+	ChatResult func(*ChatResult)
+	Event      func(Comment)
+	Error      func(error)
+}
+
+// OnThread calls f.Thread.
+func (f CommentHandlerFuncs) OnThread(t *Thread) {
+	if f.Thread != nil {
+		f.Thread(t)
+	}
+}
+
+// OnChat calls f.Chat.
+func (f CommentHandlerFuncs) OnChat(c *Chat) {
+	if f.Chat != nil {
+		f.Chat(c)
+	}
+}
+
+// OnChatResult calls f.ChatResult.
+func (f CommentHandlerFuncs) OnChatResult(r *ChatResult) {
+	if f.ChatResult != nil {
+		f.ChatResult(r)
+	}
+}
+
+// OnEvent calls f.Event.
+func (f CommentHandlerFuncs) OnEvent(cmt Comment) {
+	if f.Event != nil {
+		f.Event(cmt)
+	}
+}
+
+// OnError calls f.Error.
+func (f CommentHandlerFuncs) OnError(err error) {
+	if f.Error != nil {
+		f.Error(err)
+	}
+}
+
+// Subscribe receives the comments like StreamingComment and passes them to handler
+// in order through the buffer set by WithBuffer. It blocks until reading fails or
+// ctx is done, then calls OnError with the cause and returns it.
+// The other comments than Thread, Chat and ChatResult are passed to OnEvent
+// if handler implements CommentEventHandler. ErrEventHandlerRequired is returned
+// if it does not and WithReconnect, WithBackfill or WithStallTimeout is given,
+// not to lose the events of them.
+// The reading goroutine is stopped before Subscribe returns.
+func (c *LiveClient) Subscribe(ctx context.Context, resFrom int64, handler CommentHandler, opts ...StreamOption) error {
+	cfg := streamConfig{bufferSize: DefaultSubscribeBuffer}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.bufferSize <= 0 {
+		cfg.bufferSize = 1
+	}
+	if _, ok := handler.(CommentEventHandler); !ok && (cfg.reconnect != nil || cfg.backfill > 0 || cfg.stallTimeout > 0) {
+		return ErrEventHandlerRequired
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	ch, err := c.StreamingComment(ctx, resFrom, opts...)
+	if err != nil {
+		return err
+	}
+
+	buf := make(chan Comment, cfg.bufferSize)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(buf)
+		bufferComments(ctx, cancel, ch, buf, cfg.overflow)
+	}()
+
+	err = dispatchComments(ctx, buf, handler)
+	// Stop reading and wait for the goroutines.
+	cancel(nil)
+	for range buf {
+	}
+	wg.Wait()
+
+	handler.OnError(err)
+	return err
+}
+
+// bufferComments sends the comments from in to buf by policy until in is closed.
+func bufferComments(ctx context.Context, cancel context.CancelCauseFunc, in <-chan Comment, buf chan Comment, policy OverflowPolicy) {
+	for cmt := range in {
+		if ctx.Err() != nil {
+			// Drain in until the reading goroutine ends.
+			continue
+		}
+		select {
+		case buf <- cmt:
+			continue
+		default:
+		}
+
+		switch policy {
+		case OverflowDropOldest:
+			select {
+			case <-buf:
+			default:
+			}
+			// Only this goroutine sends to buf, so there is room.
+			buf <- cmt
+		case OverflowFail:
+			cancel(ErrCommentOverflow)
+		default:
+			select {
+			case buf <- cmt:
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+// dispatchComments passes the comments from buf to handler and returns
+// the error that ends the subscription.
+func dispatchComments(ctx context.Context, buf <-chan Comment, handler CommentHandler) error {
+	for cmt := range buf {
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		switch v := cmt.(type) {
+		case *Thread:
+			handler.OnThread(v)
+		case *Chat:
+			handler.OnChat(v)
+		case *ChatResult:
+			handler.OnChatResult(v)
+		case *CommentError:
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+			return v.error
+		default:
+			if h, ok := handler.(CommentEventHandler); ok {
+				h.OnEvent(v)
+			}
+		}
+	}
+	return context.Cause(ctx)
+}
@@ -0,0 +1,211 @@
+package nico
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLiveClient_Subscribe(t *testing.T) {
+	requests := make(chan SendThread, 10)
+	ln := newScriptedCommentServer(t, requests, scriptedConn{
+		frames: []string{`<thread thread="100"/>`, chat(1), `<ping>rs:0</ping>`, chat(2), `<chat_result thread="100" status="0" no="3"/>`},
+		close:  true,
+	})
+	defer ln.Close()
+	lc := newTestLiveClient(t, ln)
+
+	var got []string
+	var gotErr error
+	err := lc.Subscribe(context.Background(), 0, CommentHandlerFuncs{
+		Thread:     func(*Thread) { got = append(got, "thread") },
+		Chat:       func(c *Chat) { got = append(got, c.Comment) },
+		ChatResult: func(*ChatResult) { got = append(got, "chat_result") },
+		Error:      func(err error) { gotErr = err },
+	})
+	if err != io.EOF {
+		t.Fatalf("want %v but %v", io.EOF, err)
+	}
+	if gotErr != io.EOF {
+		t.Fatalf("want %v but %v", io.EOF, gotErr)
+	}
+	want := []string{"thread", "c1", "c2", "chat_result"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v but %v", want, got)
+	}
+}
+
+func TestLiveClient_SubscribeCancel(t *testing.T) {
+	requests := make(chan SendThread, 10)
+	ln := newScriptedCommentServer(t, requests, scriptedConn{frames: []string{chat(1), chat(2), chat(3)}})
+	defer ln.Close()
+	lc := newTestLiveClient(t, ln)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		// The handler is slower than the stream.
+		done <- lc.Subscribe(ctx, 0, CommentHandlerFuncs{Chat: func(*Chat) { cancel() }}, WithBuffer(1, OverflowBlock))
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("want %v but %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out")
+	}
+}
+
+func TestBufferComments(t *testing.T) {
+	comments := func(nos ...int64) chan Comment {
+		ch := make(chan Comment, len(nos))
+		for _, no := range nos {
+			ch <- &Chat{No: no}
+		}
+		close(ch)
+		return ch
+	}
+	nos := func(buf chan Comment) []int64 {
+		close(buf)
+		var ns []int64
+		for cmt := range buf {
+			ns = append(ns, cmt.(*Chat).No)
+		}
+		return ns
+	}
+
+	t.Run("DropOldest", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+		buf := make(chan Comment, 2)
+		bufferComments(ctx, cancel, comments(1, 2, 3, 4, 5), buf, OverflowDropOldest)
+		if got, want := nos(buf), []int64{4, 5}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("want %v but %v", want, got)
+		}
+	})
+
+	t.Run("Fail", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+		buf := make(chan Comment, 2)
+		bufferComments(ctx, cancel, comments(1, 2, 3, 4, 5), buf, OverflowFail)
+		if err := context.Cause(ctx); err != ErrCommentOverflow {
+			t.Fatalf("want %v but %v", ErrCommentOverflow, err)
+		}
+		if got, want := nos(buf), []int64{1, 2}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("want %v but %v", want, got)
+		}
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		buf := make(chan Comment, 2)
+		done := make(chan struct{})
+		go func() {
+			bufferComments(ctx, cancel, comments(1, 2, 3, 4, 5), buf, OverflowBlock)
+			close(done)
+		}()
+		if cmt := <-buf; cmt.(*Chat).No != 1 {
+			t.Fatalf("want %d but %d", 1, cmt.(*Chat).No)
+		}
+		// Blocked until canceled.
+		cancel(nil)
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out")
+		}
+	})
+}
+
+func TestLiveClient_SubscribeOverflow(t *testing.T) {
+	frames := []string{`<thread thread="100"/>`, chat(1), chat(2), chat(3), chat(4), chat(5)}
+
+	t.Run("DropOldest", func(t *testing.T) {
+		requests := make(chan SendThread, 10)
+		ln := newScriptedCommentServer(t, requests, scriptedConn{frames: frames, close: true})
+		defer ln.Close()
+		lc := newTestLiveClient(t, ln)
+
+		var got []string
+		err := lc.Subscribe(context.Background(), 0, CommentHandlerFuncs{
+			// The handler is slower than the stream.
+			Thread: func(*Thread) { time.Sleep(300 * time.Millisecond) },
+			Chat:   func(c *Chat) { got = append(got, c.Comment) },
+		}, WithBuffer(2, OverflowDropOldest))
+		if err != io.EOF {
+			t.Fatalf("want %v but %v", io.EOF, err)
+		}
+		if want := []string{"c5"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("want %v but %v", want, got)
+		}
+	})
+
+	t.Run("Fail", func(t *testing.T) {
+		requests := make(chan SendThread, 10)
+		ln := newScriptedCommentServer(t, requests, scriptedConn{frames: frames})
+		defer ln.Close()
+		lc := newTestLiveClient(t, ln)
+
+		var gotErr error
+		err := lc.Subscribe(context.Background(), 0, CommentHandlerFuncs{
+			Thread: func(*Thread) { time.Sleep(300 * time.Millisecond) },
+			Chat:   func(c *Chat) { t.Fatalf("should not receive the chat after the overflow: %+v", c) },
+			Error:  func(err error) { gotErr = err },
+		}, WithBuffer(1, OverflowFail))
+		if err != ErrCommentOverflow {
+			t.Fatalf("want %v but %v", ErrCommentOverflow, err)
+		}
+		if gotErr != ErrCommentOverflow {
+			t.Fatalf("want %v but %v", ErrCommentOverflow, gotErr)
+		}
+	})
+}
+
+// chatHandler is CommentHandler without OnEvent.
+type chatHandler struct{}
+
+func (chatHandler) OnThread(*Thread)         {}
+func (chatHandler) OnChat(*Chat)             {}
+func (chatHandler) OnChatResult(*ChatResult) {}
+func (chatHandler) OnError(error)            {}
+
+func TestLiveClient_SubscribeEvent(t *testing.T) {
+	requests := make(chan SendThread, 10)
+	ln := newScriptedCommentServer(t, requests, scriptedConn{frames: []string{`<ping>rs:0</ping>`}})
+	defer ln.Close()
+	lc := newTestLiveClient(t, ln)
+
+	if err := lc.Subscribe(context.Background(), 0, chatHandler{}, WithStallTimeout(time.Second)); err != ErrEventHandlerRequired {
+		t.Fatalf("want %v but %v", ErrEventHandlerRequired, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var got []Comment
+	err := lc.Subscribe(ctx, 0, CommentHandlerFuncs{
+		Event: func(cmt Comment) {
+			got = append(got, cmt)
+			if _, ok := cmt.(*Stalled); ok {
+				cancel()
+			}
+		},
+	}, WithStallTimeout(50*time.Millisecond))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want %v but %v", context.Canceled, err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("want %d but %d: %+v", 2, len(got), got)
+	}
+	if f, ok := got[0].(*RawFrame); !ok || f.Name != "ping" {
+		t.Fatalf("want RawFrame of ping but %+v", got[0])
+	}
+	if _, ok := got[1].(*Stalled); !ok {
+		t.Fatalf("want *Stalled but %+v", got[1])
+	}
+}
@@ -0,0 +1,176 @@
+package nico
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultWatchInterval is the polling interval of WatchPlayerStatus
+// used when the given interval is not positive.
+const DefaultWatchInterval = 10 * time.Second
+
+// watchMaxIntervalFactor is the upper limit of the polling interval of
+// WatchPlayerStatus relative to the given interval.
+const watchMaxIntervalFactor = 8
+
+// PlayerStatusEvent is an event sent by WatchPlayerStatus.
+type PlayerStatusEvent interface {
+	playerStatusEvent()
+}
+
+// WatchCountChanged is sent when the number of the viewers is changed.
+type WatchCountChanged struct {
+	Old, New     int64
+	PlayerStatus *PlayerStatus
+}
+
+func (e *WatchCountChanged) playerStatusEvent() {}
+
+// CommentCountChanged is sent when the number of the comments is changed.
+type CommentCountChanged struct {
+	Old, New     int64
+	PlayerStatus *PlayerStatus
+}
+
+func (e *CommentCountChanged) playerStatusEvent() {}
+
+// TitleChanged is sent when the title of the broadcast is changed.
+type TitleChanged struct {
+	Old, New     string
+	PlayerStatus *PlayerStatus
+}
+
+func (e *TitleChanged) playerStatusEvent() {}
+
+// BroadcastEnded is sent when the broadcast is ended.
+// PlayerStatus is the last status got, and nil if the broadcast was closed before the first one.
+// It is the last event sent by WatchPlayerStatus.
+type BroadcastEnded struct {
+	PlayerStatus *PlayerStatus
+}
+
+func (e *BroadcastEnded) playerStatusEvent() {}
+
+// StatusError is sent when GetPlayerStatus fails.
+// WatchPlayerStatus continues polling after it.
+type StatusError struct {
+	Err error
+}
+
+func (e *StatusError) playerStatusEvent() {}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of GetPlayerStatus.
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// WatchPlayerStatus polls the player status of liveID and returns the channel
+// that receives the changes of it. The polling interval starts from interval and
+// becomes longer while nothing is changed or GetPlayerStatus fails.
+// DefaultWatchInterval is used if interval is not positive.
+// The channel is closed after BroadcastEnded is sent or ctx is done.
+func (c *Client) WatchPlayerStatus(ctx context.Context, liveID string, interval time.Duration) <-chan PlayerStatusEvent {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ch := make(chan PlayerStatusEvent)
+	go func() {
+		defer close(ch)
+		w := playerStatusWatcher{client: c, liveID: liveID, interval: interval, now: time.Now}
+		w.run(ctx, ch)
+	}()
+	return ch
+}
+
+type playerStatusWatcher struct {
+	client   *Client
+	liveID   string
+	interval time.Duration
+	now      func() time.Time
+	last     *PlayerStatus
+}
+
+func (w *playerStatusWatcher) run(ctx context.Context, ch chan<- PlayerStatusEvent) {
+	maxInterval := w.interval * watchMaxIntervalFactor
+	next := w.interval
+	for {
+		events, ended := w.poll(ctx)
+		for _, e := range events {
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if ended {
+			return
+		}
+
+		if len(events) == 0 || isStatusError(events) {
+			next *= 2
+			if next > maxInterval {
+				next = maxInterval
+			}
+		} else {
+			next = w.interval
+		}
+
+		d := next
+		if w.last != nil && w.last.Stream.EndTime != 0 {
+			// Poll at the end time not to miss the end of the broadcast.
+			if untilEnd := w.last.EndAt().Sub(w.now()); untilEnd < d {
+				d = untilEnd
+			}
+		}
+		if err := sleep(ctx, d); err != nil {
+			return
+		}
+	}
+}
+
+// poll gets the player status and returns the events of the changes.
+func (w *playerStatusWatcher) poll(ctx context.Context) ([]PlayerStatusEvent, bool) {
+	ps, err := w.client.GetPlayerStatus(ctx, w.liveID)
+	if err != nil {
+		if errors.Is(err, ErrLiveClosed) || errors.Is(err, ErrLiveDeleted) {
+			return []PlayerStatusEvent{&BroadcastEnded{PlayerStatus: w.last}}, true
+		}
+		if ctx.Err() != nil {
+			return nil, true
+		}
+		return []PlayerStatusEvent{&StatusError{Err: err}}, false
+	}
+
+	var events []PlayerStatusEvent
+	if last := w.last; last != nil {
+		if last.Stream.WatchCount != ps.Stream.WatchCount {
+			events = append(events, &WatchCountChanged{Old: last.Stream.WatchCount, New: ps.Stream.WatchCount, PlayerStatus: ps})
+		}
+		if last.Stream.CommentCount != ps.Stream.CommentCount {
+			events = append(events, &CommentCountChanged{Old: last.Stream.CommentCount, New: ps.Stream.CommentCount, PlayerStatus: ps})
+		}
+		if last.Stream.Title != ps.Stream.Title {
+			events = append(events, &TitleChanged{Old: last.Stream.Title, New: ps.Stream.Title, PlayerStatus: ps})
+		}
+	}
+	w.last = ps
+
+	if ps.Stream.EndTime != 0 && !w.now().Before(ps.EndAt()) {
+		return append(events, &BroadcastEnded{PlayerStatus: ps}), true
+	}
+	return events, false
+}
+
+func isStatusError(events []PlayerStatusEvent) bool {
+	for _, e := range events {
+		if _, ok := e.(*StatusError); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package nico
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_WatchPlayerStatus(t *testing.T) {
+	end := time.Now().Add(time.Hour).Unix()
+	responses := []string{
+		fmt.Sprintf(`<getplayerstatus status="ok"><stream><title>foo</title><watch_count>10</watch_count><comment_count>5</comment_count><end_time>%d</end_time></stream></getplayerstatus>`, end),
+		fmt.Sprintf(`<getplayerstatus status="ok"><stream><title>foo</title><watch_count>10</watch_count><comment_count>5</comment_count><end_time>%d</end_time></stream></getplayerstatus>`, end),
+		fmt.Sprintf(`<getplayerstatus status="ok"><stream><title>bar</title><watch_count>20</watch_count><comment_count>5</comment_count><end_time>%d</end_time></stream></getplayerstatus>`, end),
+		`<getplayerstatus status="fail"><error><code>unknown</code></error></getplayerstatus>`,
+		fmt.Sprintf(`<getplayerstatus status="ok"><stream><title>bar</title><watch_count>20</watch_count><comment_count>8</comment_count><end_time>%d</end_time></stream></getplayerstatus>`, time.Now().Add(-time.Second).Unix()),
+	}
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(&n, 1)) - 1
+		if i >= len(responses) {
+			i = len(responses) - 1
+		}
+		fmt.Fprint(w, responses[i])
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithLiveBaseURL(ts.URL))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var events []PlayerStatusEvent
+	for e := range c.WatchPlayerStatus(ctx, "lv1234567", 10*time.Millisecond) {
+		events = append(events, e)
+	}
+	if len(events) != 5 {
+		t.Fatalf("want %d but %d: %+v", 5, len(events), events)
+	}
+	if e, ok := events[0].(*WatchCountChanged); !ok || e.Old != 10 || e.New != 20 {
+		t.Fatalf("want WatchCountChanged 10 -> 20 but %+v", events[0])
+	}
+	if e, ok := events[1].(*TitleChanged); !ok || e.Old != "foo" || e.New != "bar" {
+		t.Fatalf("want TitleChanged foo -> bar but %+v", events[1])
+	}
+	var pse PlayerStatusError
+	if e, ok := events[2].(*StatusError); !ok || !errors.As(e, &pse) || pse.Code != "unknown" {
+		t.Fatalf("want StatusError but %+v", events[2])
+	}
+	if e, ok := events[3].(*CommentCountChanged); !ok || e.Old != 5 || e.New != 8 {
+		t.Fatalf("want CommentCountChanged 5 -> 8 but %+v", events[3])
+	}
+	if e, ok := events[4].(*BroadcastEnded); !ok || e.PlayerStatus == nil {
+		t.Fatalf("want BroadcastEnded but %+v", events[4])
+	}
+}
+
+func TestClient_WatchPlayerStatus_Closed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<getplayerstatus status="fail"><error><code>closed</code></error></getplayerstatus>`)
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithLiveBaseURL(ts.URL))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var events []PlayerStatusEvent
+	for e := range c.WatchPlayerStatus(ctx, "lv1234567", time.Hour) {
+		events = append(events, e)
+	}
+	if len(events) != 1 {
+		t.Fatalf("want %d but %d: %+v", 1, len(events), events)
+	}
+	if e, ok := events[0].(*BroadcastEnded); !ok || e.PlayerStatus != nil {
+		t.Fatalf("want BroadcastEnded but %+v", events[0])
+	}
+}
+
+func TestClient_WatchPlayerStatus_Cancel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<getplayerstatus status="ok"><stream><title>foo</title></stream></getplayerstatus>`)
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithLiveBaseURL(ts.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := c.WatchPlayerStatus(ctx, "lv1234567", time.Hour)
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatalf("should not receive any event")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the channel should be closed on cancel")
+	}
+}
+
+func TestClient_WatchPlayerStatus_ZeroInterval(t *testing.T) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		fmt.Fprint(w, `<getplayerstatus status="ok"><stream><title>foo</title></stream></getplayerstatus>`)
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithLiveBaseURL(ts.URL))
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	for range c.WatchPlayerStatus(ctx, "lv1234567", 0) {
+	}
+	// DefaultWatchInterval is used instead of polling in a loop.
+	if got := atomic.LoadInt32(&n); got != 1 {
+		t.Fatalf("want %d but %d", 1, got)
+	}
+}